@@ -0,0 +1,123 @@
+package gsvt
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Adopt builds a DB over an existing table, ie one an
+// application already stores embeddings in, without creating
+// or migrating it; Migrate and AddField are refused. The
+// table's schema is read via FromSQL, and column - a BLOB
+// column holding vectors as per config - becomes the
+// collection's default field, searched when
+// FilterOptions.Field is empty. If column is "" it is
+// VECTOR_COLUMN_NAME.
+//
+// Whatever its column, the default field is held in
+// Vector.Vector, so an adopted table is inserted into and
+// searched like any other. Every stored value is checked to
+// decode; see ValidateField.
+func Adopt(db *sql.DB, table string, column string, config *VectorConfig) (*DB, error) {
+	if column == "" {
+		column = VECTOR_COLUMN_NAME
+	}
+
+	schema, err := FromSQL(db, table)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+
+	if err := validateAdoptedColumn(schema, column); err != nil {
+		return nil, err
+	}
+	if _, err := encodingSize(config.Encoding); err != nil {
+		return nil, err
+	}
+	if config.Sparse {
+		if err := validateAdoptedColumn(schema, SPARSE_VECTOR_COLUMN_NAME); err != nil {
+			return nil, err
+		}
+		if config.SparsePostings {
+			return nil, fmt.Errorf("sparse posting lists require a migrated table")
+		}
+	}
+
+	vectorDB := newDB(db, schema, config, false)
+	vectorDB.fields[VECTOR_COLUMN_NAME] = config
+	vectorDB.vectorColumn = column
+	vectorDB.adopted = true
+
+	if err := vectorDB.ValidateField(""); err != nil {
+		return nil, err
+	}
+
+	return vectorDB, nil
+}
+
+// validateAdoptedColumn ensures the schema has the column
+// and that it can hold BLOBs; SQLite columns declared
+// without a type can hold anything.
+func validateAdoptedColumn(schema *Schema, name string) error {
+	for _, column := range schema.Columns {
+		if column.Name != name {
+			continue
+		}
+		columnType := strings.ToUpper(column.Type)
+		if columnType != "BLOB" && columnType != "" {
+			return fmt.Errorf("column %s is of type %s, not BLOB", name, column.Type)
+		}
+		return nil
+	}
+	return fmt.Errorf("column %s does not exist in table %s", name, schema.Name)
+}
+
+// ValidateField checks that every stored value of the field
+// is a BLOB which decodes to a vector valid for the field's
+// config. NULL values are skipped. The first invalid row is
+// reported by its rowid.
+func (db *DB) ValidateField(name string) error {
+	config, err := db.field(name)
+	if err != nil {
+		return err
+	}
+	name = db.fieldColumn(db.resolveField(name))
+
+	query := fmt.Sprintf(
+		"SELECT rowid, %s FROM %s WHERE %s IS NOT NULL",
+		name,
+		db.schema.Name,
+		name,
+	)
+	rows, err := db.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rowID int64
+		var value interface{}
+		if err := rows.Scan(&rowID, &value); err != nil {
+			return err
+		}
+
+		bytes, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("rowid %d: vector field %s does not hold a BLOB", rowID, name)
+		}
+		vector, err := decodeVector(bytes, config.Encoding)
+		if err != nil {
+			return fmt.Errorf("rowid %d: vector field %s: %v", rowID, name, err)
+		}
+		if err := validateFieldVector(name, config, vector); err != nil {
+			return fmt.Errorf("rowid %d: %v", rowID, err)
+		}
+	}
+
+	return rows.Err()
+}
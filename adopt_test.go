@@ -0,0 +1,125 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdopt(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// A table some other application created and fills
+	_, err = sqlite.Exec(`CREATE TABLE articles (
+		id INTEGER PRIMARY KEY,
+		title TEXT NOT NULL,
+		embedding BLOB
+	)`)
+	require.Nil(t, err)
+	for index, embedding := range []govector.Vector{
+		{1, 0, 0},
+		{0, 1, 0},
+		{0.9, 0.1, 0},
+	} {
+		_, err = sqlite.Exec(
+			"INSERT INTO articles (id, title, embedding) VALUES (?, ?, ?)",
+			index+1,
+			"article",
+			encodeVector(embedding, ENCODING_FLOAT32),
+		)
+		require.Nil(t, err)
+	}
+	_, err = sqlite.Exec("INSERT INTO articles (id, title) VALUES (4, 'unembedded')")
+	require.Nil(t, err)
+
+	config := &VectorConfig{Length: 3, Encoding: ENCODING_FLOAT32}
+	db, err := Adopt(sqlite, "articles", "embedding", config)
+	require.Nil(t, err)
+	assert.Equal(t, []string{VECTOR_COLUMN_NAME}, db.Fields())
+
+	// The adopted column is the default field, searched by
+	// default and held in Vector.Vector
+	target := &Vector{Vector: govector.Vector{1, 0, 0}}
+	results, err := db.Search(target, nil, &FilterOptions{SimilarityOptions: DefaultSimilarityOptions})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+	assert.Equal(t, int64(1), results.Results[0].Vector.Metadata["id"])
+	assert.Equal(t, int64(3), results.Results[1].Vector.Metadata["id"])
+	assert.Equal(t, govector.Vector{1, 0, 0}, results.Results[0].Vector.Vector)
+	assert.Nil(t, results.Results[0].Vector.Fields)
+	assert.Nil(t, results.Results[0].Vector.Metadata["embedding"])
+
+	// ...and may be named by its column
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Field:             "embedding",
+	})
+	require.Nil(t, err)
+	assert.Len(t, results.Results, 3)
+
+	byID, err := db.QuerySimilarityByID(int64(1), nil, &FilterOptions{SimilarityOptions: DefaultSimilarityOptions})
+	require.Nil(t, err)
+	assert.Len(t, byID.Results, 2)
+
+	// Inserts write to the existing column, in its encoding
+	inserted := &Vector{
+		Metadata: map[string]interface{}{"title": "new"},
+		Vector:   govector.Vector{0, 0, 1},
+	}
+	require.Nil(t, db.Insert(inserted))
+	stored, err := db.GetByRowID(inserted.RowID)
+	require.Nil(t, err)
+	assert.Equal(t, govector.Vector{0, 0, 1}, stored[0].Vector)
+	var raw []byte
+	require.Nil(t, sqlite.QueryRow("SELECT embedding FROM articles WHERE title = 'new'").Scan(&raw))
+	assert.Equal(t, encodeVector(govector.Vector{0, 0, 1}, ENCODING_FLOAT32), raw)
+	assert.NotNil(t, db.Insert(&Vector{
+		Metadata: map[string]interface{}{"title": "new"},
+		Fields:   map[string]govector.Vector{"embedding": {0, 0, 1}},
+	}))
+	require.Nil(t, db.SetField(inserted.RowID, "", govector.Vector{0, 1, 0}))
+
+	// Matryoshka prefixes are read from the adopted column
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Matryoshka:        &MatryoshkaOptions{Dimensions: 2},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), results.Results[0].Vector.Metadata["id"])
+
+	// The column can't be filtered on
+	_, err = Where("embedding").Eq([]byte{1}).Build(db)
+	assert.NotNil(t, err)
+	_, err = db.Query(&Filter{Metadata: []ColumnFilter{
+		{Column: "embedding", Operation: OP_EQUAL, Value: []byte{1}},
+	}})
+	assert.NotNil(t, err)
+
+	// Adopted tables are left as they are
+	assert.NotNil(t, db.Migrate())
+	assert.NotNil(t, db.AddField("summary", &VectorConfig{Length: 3}))
+
+	// A mismatched config, or column, is caught up front
+	_, err = Adopt(sqlite, "articles", "embedding", &VectorConfig{Length: 3})
+	assert.NotNil(t, err)
+	_, err = Adopt(sqlite, "articles", "embedding", &VectorConfig{Length: 4, Encoding: ENCODING_FLOAT32})
+	assert.NotNil(t, err)
+	_, err = Adopt(sqlite, "articles", "title", config)
+	assert.NotNil(t, err)
+	_, err = Adopt(sqlite, "articles", "missing", config)
+	assert.NotNil(t, err)
+	_, err = Adopt(sqlite, "missing", "embedding", config)
+	assert.NotNil(t, err)
+
+	// Values that later go bad are reported by rowid
+	_, err = sqlite.Exec("UPDATE articles SET embedding = X'0102' WHERE id = 2")
+	require.Nil(t, err)
+	err = db.ValidateField("")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "rowid 2")
+}
@@ -0,0 +1,235 @@
+package gsvt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Aggregate functions supported within an Aggregation
+const AGG_COUNT = "COUNT"
+const AGG_MIN = "MIN"
+const AGG_MAX = "MAX"
+const AGG_SUM = "SUM"
+const AGG_AVG = "AVG"
+
+var validAggregateFunctions = map[string]bool{
+	AGG_COUNT: true,
+	AGG_MIN:   true,
+	AGG_MAX:   true,
+	AGG_SUM:   true,
+	AGG_AVG:   true,
+}
+
+// timestampFormats are the formats SQLite (and go-sqlite3)
+// write timestamps in. Aggregates lose their column's
+// declared type, so we have to parse MIN/MAX timestamps
+// back ourselves.
+var timestampFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Aggregation is a single aggregate function to calculate
+// over a column, ie MAX(created_at)
+type Aggregation struct {
+	// Function is one of the AGG_* constants
+	Function string
+
+	// Column is the column to aggregate. It can be left
+	// empty for AGG_COUNT to count rows.
+	Column string
+
+	// Name is the key the result is stored under in the
+	// AggregateResult. If empty, it defaults to the SQL
+	// expression, ie "MAX(created_at)" or "COUNT(*)".
+	Name string
+}
+
+// AggregateResult is the result of a set of aggregations
+// for a single group.
+type AggregateResult struct {
+	// Group holds the value of each group by column for
+	// this group. It is empty if there was no grouping.
+	Group map[string]interface{}
+
+	// Values holds the result of each aggregation, keyed
+	// by its name
+	Values map[string]interface{}
+}
+
+// Count returns how many vectors match the given filter. If
+// the filter is nil then all vectors are counted.
+func (db *DB) Count(filter *Filter) (int64, error) {
+	if err := db.validateQueryFilter(filter); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s ", db.schema.Name)
+	whereClause, whereValues := buildWhereClause(filter)
+	if whereClause != "" {
+		query += fmt.Sprintf("WHERE %s ", whereClause)
+	}
+
+	var count int64
+	err := db.db.QueryRow(query, whereValues...).Scan(&count)
+	return count, err
+}
+
+func (db *DB) validateAggregate(groupBy []string, aggregations []Aggregation) error {
+	allColumns := map[string]bool{}
+	for _, column := range db.schema.Columns {
+		allColumns[column.Name] = true
+	}
+
+	for _, column := range groupBy {
+		if !allColumns[column] {
+			return fmt.Errorf("column %s does not exist", column)
+		} else if db.isVectorColumn(column) {
+			return fmt.Errorf("you can not group by %s", column)
+		}
+	}
+
+	if len(aggregations) == 0 {
+		return fmt.Errorf("at least one aggregation is required")
+	}
+	for _, aggregation := range aggregations {
+		function := strings.ToUpper(aggregation.Function)
+		if !validAggregateFunctions[function] {
+			return fmt.Errorf("aggregate function %s is not supported", aggregation.Function)
+		}
+		if aggregation.Column == "" {
+			if function != AGG_COUNT {
+				return fmt.Errorf("aggregate function %s requires a column", function)
+			}
+		} else if !allColumns[aggregation.Column] {
+			return fmt.Errorf("column %s does not exist", aggregation.Column)
+		} else if db.isVectorColumn(aggregation.Column) {
+			return fmt.Errorf("you can not aggregate %s", aggregation.Column)
+		}
+	}
+
+	return nil
+}
+
+// Aggregate calculates the aggregations over all vectors
+// matching the filter, grouped by the distinct values of the
+// groupBy columns. If groupBy is empty, a single result
+// covering all matching vectors is returned. Groups are
+// ordered by their groupBy values. Vector blobs are never
+// loaded.
+func (db *DB) Aggregate(filter *Filter, groupBy []string, aggregations []Aggregation) ([]*AggregateResult, error) {
+	if err := db.validateQueryFilter(filter); err != nil {
+		return nil, err
+	}
+	if err := db.validateAggregate(groupBy, aggregations); err != nil {
+		return nil, err
+	}
+
+	// Build our SELECT clause - group columns first, then
+	// each aggregation
+	expressions := append([]string{}, groupBy...)
+	for _, aggregation := range aggregations {
+		expressions = append(expressions, aggregation.expression())
+	}
+	selectClause := strings.Join(expressions, ", ")
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s ",
+		selectClause,
+		db.schema.Name,
+	)
+	whereClause, whereValues := buildWhereClause(filter)
+	if whereClause != "" {
+		query += fmt.Sprintf("WHERE %s ", whereClause)
+	}
+	if len(groupBy) > 0 {
+		groupClause := strings.Join(groupBy, ", ")
+		query += fmt.Sprintf("GROUP BY %s ORDER BY %s ", groupClause, groupClause)
+	}
+
+	rows, err := db.db.Query(query, whereValues...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*AggregateResult{}
+	for rows.Next() {
+		values := make([]interface{}, len(expressions))
+		scanTargets := make([]interface{}, len(expressions))
+		for i := range values {
+			scanTargets[i] = &values[i]
+		}
+		if err := rows.Scan(scanTargets...); err != nil {
+			return nil, err
+		}
+
+		result := &AggregateResult{
+			Group:  map[string]interface{}{},
+			Values: map[string]interface{}{},
+		}
+		for index, column := range groupBy {
+			result.Group[column] = values[index]
+		}
+		for index, aggregation := range aggregations {
+			value := values[len(groupBy)+index]
+			result.Values[aggregation.name()] = db.convertAggregateValue(aggregation, value)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// expression generates the SQL for the aggregation
+func (a Aggregation) expression() string {
+	column := a.Column
+	if column == "" {
+		column = "*"
+	}
+	return fmt.Sprintf("%s(%s)", strings.ToUpper(a.Function), column)
+}
+
+func (a Aggregation) name() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.expression()
+}
+
+// convertAggregateValue attempts to return MIN and MAX of
+// TIMESTAMP columns as time.Time, as SQLite hands them back
+// as plain text.
+func (db *DB) convertAggregateValue(aggregation Aggregation, value interface{}) interface{} {
+	function := strings.ToUpper(aggregation.Function)
+	if function != AGG_MIN && function != AGG_MAX {
+		return value
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	for _, column := range db.schema.Columns {
+		if column.Name == aggregation.Column && column.Type == "TIMESTAMP" {
+			text = strings.TrimSuffix(text, "Z")
+			for _, format := range timestampFormats {
+				if timestamp, err := time.ParseInLocation(format, text, time.UTC); err == nil {
+					return timestamp
+				}
+			}
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,110 @@
+package gsvt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCount(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Setup our db and vectors
+	db, vectors, _, err := setupVectorsAndDB(sqlite)
+	require.Nil(t, err)
+
+	count, err := db.Count(nil)
+	require.Nil(t, err)
+	assert.Equal(t, int64(len(vectors)), count)
+
+	filter := &Filter{
+		Metadata: []ColumnFilter{
+			{
+				Column:    "user",
+				Operation: "==",
+				Value:     "user2",
+			},
+		},
+	}
+	count, err = db.Count(filter)
+	require.Nil(t, err)
+	assert.Equal(t, int64(len(roughQuery(db, vectors, filter))), count)
+
+	// Filters are validated as they are in Query
+	_, err = db.Count(&Filter{
+		Metadata: []ColumnFilter{
+			{
+				Column:    "fake",
+				Operation: "==",
+				Value:     "user2",
+			},
+		},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestAggregate(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Setup our db and vectors
+	db, vectors, _, err := setupVectorsAndDB(sqlite)
+	require.Nil(t, err)
+
+	// Calculate what we expect per source by hand
+	expectedCounts := map[string]int64{}
+	expectedMax := map[string]time.Time{}
+	for _, vector := range vectors {
+		source := vector.Metadata["source"].(string)
+		createdAt := vector.Metadata["created_at"].(time.Time)
+		expectedCounts[source]++
+		if createdAt.After(expectedMax[source]) {
+			expectedMax[source] = createdAt
+		}
+	}
+
+	results, err := db.Aggregate(nil, []string{"source"}, []Aggregation{
+		{Function: AGG_COUNT},
+		{Function: AGG_MAX, Column: "created_at", Name: "latest"},
+	})
+	require.Nil(t, err)
+	require.Len(t, results, len(expectedCounts))
+
+	previous := ""
+	for _, result := range results {
+		source := result.Group["source"].(string)
+		assert.Less(t, previous, source)
+		previous = source
+
+		assert.Equal(t, expectedCounts[source], result.Values["COUNT(*)"])
+		latest, ok := result.Values["latest"].(time.Time)
+		require.True(t, ok)
+		assert.True(t, expectedMax[source].Equal(latest))
+	}
+
+	// Without grouping we get a single result for the
+	// whole filtered set
+	results, err = db.Aggregate(nil, nil, []Aggregation{
+		{Function: AGG_COUNT, Column: "text"},
+	})
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(len(vectors)), results[0].Values["COUNT(text)"])
+
+	// Bad requests are rejected
+	_, err = db.Aggregate(nil, []string{VECTOR_COLUMN_NAME}, []Aggregation{{Function: AGG_COUNT}})
+	assert.NotNil(t, err)
+	_, err = db.Aggregate(nil, nil, []Aggregation{{Function: "MEDIAN", Column: "text"}})
+	assert.NotNil(t, err)
+	_, err = db.Aggregate(nil, nil, []Aggregation{{Function: AGG_MAX}})
+	assert.NotNil(t, err)
+	_, err = db.Aggregate(nil, nil, nil)
+	assert.NotNil(t, err)
+}
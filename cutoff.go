@@ -0,0 +1,267 @@
+package gsvt
+
+import (
+	"fmt"
+	"math"
+)
+
+// CutoffStrategy decides how many of the ranked results of a
+// search are likely matches and should be kept.
+type CutoffStrategy interface {
+	// Cutoff is given the scores of every result, best
+	// first, and returns how many of them to keep along
+	// with why.
+	Cutoff(scores []float64) *Cutoff
+}
+
+// Cutoff is the decision of a CutoffStrategy
+type Cutoff struct {
+	// Strategy is the name of the strategy that decided
+	Strategy string
+
+	// Keep is how many of the results were kept
+	Keep int
+
+	// Threshold is the score the strategy cut at; results
+	// kept scored at or above it, or at or below it for
+	// distance metrics.
+	Threshold float64
+
+	// Reason is a human readable explanation of the cut
+	Reason string
+}
+
+// applyCutoff asks the strategy where to cut the (sorted)
+// results and keeps only those above the cut. Strategies
+// expect higher scores to be better, so distances are given
+// to them negated.
+func applyCutoff(results []*SearchResult, strategy CutoffStrategy, distance bool) ([]*SearchResult, *Cutoff) {
+	scores := make([]float64, len(results))
+	for index, result := range results {
+		scores[index] = result.Score
+		if distance {
+			scores[index] = -scores[index]
+		}
+	}
+
+	cutoff := strategy.Cutoff(scores)
+	if distance {
+		cutoff.Threshold = -cutoff.Threshold
+	}
+
+	// Don't trust the strategy to stay in bounds
+	if cutoff.Keep < 0 {
+		cutoff.Keep = 0
+	} else if cutoff.Keep > len(results) {
+		cutoff.Keep = len(results)
+	}
+
+	return results[0:cutoff.Keep], cutoff
+}
+
+// keepAll is the decision for when there is nothing to cut
+func keepAll(strategy string, scores []float64, reason string) *Cutoff {
+	threshold := math.Inf(-1)
+	if len(scores) > 0 {
+		threshold = scores[len(scores)-1]
+	}
+	return &Cutoff{
+		Strategy:  strategy,
+		Keep:      len(scores),
+		Threshold: threshold,
+		Reason:    reason,
+	}
+}
+
+// ===========================
+// Standard Deviation
+// ===========================
+
+// StdDeviationCutoff keeps the outliers - the results that
+// score at least StdDeviations standard deviations above the
+// mean score (a z-score cutoff).
+type StdDeviationCutoff struct {
+	StdDeviations float64
+}
+
+func (s *StdDeviationCutoff) Cutoff(scores []float64) *Cutoff {
+	name := "std_deviation"
+	if len(scores) == 0 {
+		return keepAll(name, scores, "there were no results")
+	}
+
+	mean, stdDev := meanAndStandardDeviation(scores)
+	outlier := mean + (s.StdDeviations * stdDev)
+
+	// Find the index of the first non outlier
+	for index, score := range scores {
+		if score < outlier {
+			return &Cutoff{
+				Strategy:  name,
+				Keep:      index,
+				Threshold: outlier,
+				Reason: fmt.Sprintf(
+					"score %f is below mean %f + %.2f std deviations of %f",
+					score,
+					mean,
+					s.StdDeviations,
+					stdDev,
+				),
+			}
+		}
+	}
+
+	// If nothing fell below the line then the scores are
+	// all (near enough) the same, and none are outliers
+	// relative to the rest; we can't tell them apart so
+	// keep them all
+	return keepAll(name, scores, fmt.Sprintf(
+		"no score fell below mean %f + %.2f std deviations of %f",
+		mean,
+		s.StdDeviations,
+		stdDev,
+	))
+}
+
+// ===========================
+// Knee
+// ===========================
+
+// KneeCutoff finds the "knee" or elbow of the score curve -
+// the point furthest from the straight line drawn between
+// the best and worst scores - and keeps everything up to
+// and including it.
+type KneeCutoff struct{}
+
+func (k *KneeCutoff) Cutoff(scores []float64) *Cutoff {
+	name := "knee"
+	if len(scores) < 3 {
+		return keepAll(name, scores, "too few results to find a knee")
+	}
+
+	first := scores[0]
+	last := scores[len(scores)-1]
+	if first == last {
+		return keepAll(name, scores, "all results scored the same")
+	}
+
+	// Normalize both axes to [0, 1] so that the distance is
+	// independent of the score scale. The line then runs
+	// from (0, 1) to (1, 0), and the distance of a point
+	// below it is proportional to 1 - x - y.
+	knee := 0
+	kneeDistance := math.Inf(-1)
+	for index, score := range scores {
+		x := float64(index) / float64(len(scores)-1)
+		y := (score - last) / (first - last)
+		distance := 1 - x - y
+		if distance > kneeDistance {
+			knee = index
+			kneeDistance = distance
+		}
+	}
+
+	return &Cutoff{
+		Strategy:  name,
+		Keep:      knee + 1,
+		Threshold: scores[knee],
+		Reason: fmt.Sprintf(
+			"score curve bends most sharply at result %d with score %f",
+			knee,
+			scores[knee],
+		),
+	}
+}
+
+// ===========================
+// Percentile
+// ===========================
+
+// PercentileCutoff keeps the top Percentile percent of the
+// results, ie a Percentile of 10 keeps the best 10%. At
+// least one result is always kept.
+type PercentileCutoff struct {
+	Percentile float64
+}
+
+func (p *PercentileCutoff) Cutoff(scores []float64) *Cutoff {
+	name := "percentile"
+	if len(scores) == 0 {
+		return keepAll(name, scores, "there were no results")
+	}
+
+	keep := int(math.Ceil(float64(len(scores)) * p.Percentile / 100))
+	if keep < 1 {
+		keep = 1
+	} else if keep > len(scores) {
+		keep = len(scores)
+	}
+
+	return &Cutoff{
+		Strategy:  name,
+		Keep:      keep,
+		Threshold: scores[keep-1],
+		Reason: fmt.Sprintf(
+			"kept the top %.2f%% of %d results",
+			p.Percentile,
+			len(scores),
+		),
+	}
+}
+
+// ===========================
+// Gap
+// ===========================
+
+// GapCutoff cuts at the largest drop in score between two
+// consecutive results, keeping everything before it.
+type GapCutoff struct{}
+
+func (g *GapCutoff) Cutoff(scores []float64) *Cutoff {
+	name := "gap"
+	if len(scores) < 2 {
+		return keepAll(name, scores, "too few results to find a gap")
+	}
+
+	gapIndex := 0
+	gap := math.Inf(-1)
+	for index := 1; index < len(scores); index++ {
+		drop := scores[index-1] - scores[index]
+		if drop > gap {
+			gapIndex = index
+			gap = drop
+		}
+	}
+
+	if gap <= 0 {
+		return keepAll(name, scores, "all results scored the same")
+	}
+
+	return &Cutoff{
+		Strategy:  name,
+		Keep:      gapIndex,
+		Threshold: scores[gapIndex-1],
+		Reason: fmt.Sprintf(
+			"largest drop in score of %f is between results %d and %d",
+			gap,
+			gapIndex-1,
+			gapIndex,
+		),
+	}
+}
+
+func meanAndStandardDeviation(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, value := range values {
+		variance += math.Pow(value-mean, 2)
+	}
+	variance /= float64(len(values))
+
+	return mean, math.Sqrt(variance)
+}
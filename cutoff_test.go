@@ -0,0 +1,95 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCutoffStrategies(t *testing.T) {
+	// Three strong matches followed by a long tail
+	scores := []float64{0.95, 0.94, 0.92, 0.5, 0.48, 0.47, 0.45, 0.44, 0.43, 0.42}
+
+	cutoff := (&StdDeviationCutoff{StdDeviations: 1}).Cutoff(scores)
+	assert.Equal(t, 3, cutoff.Keep)
+	assert.Equal(t, "std_deviation", cutoff.Strategy)
+	assert.NotEmpty(t, cutoff.Reason)
+
+	cutoff = (&KneeCutoff{}).Cutoff(scores)
+	assert.Equal(t, 4, cutoff.Keep)
+	assert.Equal(t, 0.5, cutoff.Threshold)
+	assert.NotEmpty(t, cutoff.Reason)
+
+	cutoff = (&GapCutoff{}).Cutoff(scores)
+	assert.Equal(t, 3, cutoff.Keep)
+	assert.Equal(t, 0.92, cutoff.Threshold)
+	assert.NotEmpty(t, cutoff.Reason)
+
+	cutoff = (&PercentileCutoff{Percentile: 25}).Cutoff(scores)
+	assert.Equal(t, 3, cutoff.Keep)
+	assert.Equal(t, 0.92, cutoff.Threshold)
+	cutoff = (&PercentileCutoff{Percentile: 1}).Cutoff(scores)
+	assert.Equal(t, 1, cutoff.Keep)
+
+	// Identical scores can't be told apart, so each
+	// strategy keeps them all
+	same := []float64{0.8, 0.8, 0.8, 0.8}
+	for _, strategy := range []CutoffStrategy{
+		&StdDeviationCutoff{StdDeviations: 1.5},
+		&KneeCutoff{},
+		&GapCutoff{},
+		&PercentileCutoff{Percentile: 100},
+	} {
+		assert.Equal(t, len(same), strategy.Cutoff(same).Keep)
+		assert.Equal(t, 0, strategy.Cutoff([]float64{}).Keep)
+	}
+}
+
+func TestSearchCutoff(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{1.0, 0.05, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		StdDeviations:     1.5,
+		Cutoff:            &GapCutoff{},
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	require.NotNil(t, results.Cutoff)
+	assert.Equal(t, "gap", results.Cutoff.Strategy)
+	assert.Equal(t, 2, results.Cutoff.Keep)
+
+	// StdDeviations alone still works as it always has,
+	// and explains itself
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		StdDeviations:     0.5,
+	})
+	require.Nil(t, err)
+	require.NotNil(t, results.Cutoff)
+	assert.Equal(t, "std_deviation", results.Cutoff.Strategy)
+	assert.Len(t, results.Results, results.Cutoff.Keep)
+
+	// No cutoff means no explanation
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+	})
+	require.Nil(t, err)
+	assert.Nil(t, results.Cutoff)
+	assert.Len(t, results.Results, len(vectors))
+}
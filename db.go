@@ -3,23 +3,71 @@ package gsvt
 import (
 	"database/sql"
 	"fmt"
-	"math"
-	"sort"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/drewlanenga/govector"
 )
 
+// VECTOR_COLUMN_NAME is the column of the default vector
+// field, held in Vector.Vector
 const VECTOR_COLUMN_NAME = "vector"
 
+// ROWID_COLUMN_NAME is the name SQLite's implicit rowid is
+// selected as; it acts as the identity of a stored vector.
+const ROWID_COLUMN_NAME = "rowid"
+
 type DB struct {
 	db     *sql.DB
 	schema *Schema
 	config *VectorConfig
+
+	// fields are the configs of each vector field, keyed
+	// by name; the default field is VECTOR_COLUMN_NAME, and
+	// any other is stored in a column of the same name
+	fields map[string]*VectorConfig
+
+	// vectorColumn is the column the default field, ie
+	// Vector.Vector, is stored in; VECTOR_COLUMN_NAME unless
+	// the table was adopted
+	vectorColumn string
+
+	// adopted is set for DBs over existing tables, which
+	// gsvt never migrates. See Adopt.
+	adopted bool
 }
 
 type VectorConfig struct {
 	Length int
+
+	// Method, if set, is the metric searches of the field
+	// always use, overriding SimilarityOptions.Method.
+	Method *int
+
+	// Encoding is how the field's vectors are stored; one
+	// of ENCODING_FLOAT64 (the default) or ENCODING_FLOAT32.
+	Encoding int
+
+	// MultiVector, if set, makes the field hold a packed
+	// matrix of Length long rows per vector, ie a token
+	// embedding each, scored by MaxSim. See PackMultiVector.
+	MultiVector bool
+
+	// Sparse, if set, adds a SPARSE_VECTOR_COLUMN_NAME
+	// column storing each Vector's Sparse vector.
+	Sparse bool
+
+	// SparsePostings, if set alongside Sparse, has Migrate
+	// maintain a posting list table of each non-zero sparse
+	// dimension so that SparseSearch only loads rows
+	// sharing a dimension with its target.
+	SparsePostings bool
+
+	// Model optionally names the embedding model the field's
+	// vectors come from. It is only recorded, and checked on
+	// opening, by a Store.
+	Model string
 }
 
 type Filter struct {
@@ -29,16 +77,92 @@ type Filter struct {
 type FilterOptions struct {
 	SimilarityOptions *SimilarityOptions
 
+	// Field is the vector field to search. If empty, the
+	// default VECTOR_COLUMN_NAME field is searched.
+	Field string
+
 	// StdDeviations is how many standard deviations
 	// to accept as a high likelihood match. If this
 	// is set to 0 then it will be ignored. By default
 	// the value should be ~ 1.5. However, if you have
 	// a low number of samples total, it's possible
-	// that you will wish to ignore this feature.
+	// that you will wish to ignore this feature. It is
+	// shorthand for a Cutoff of StdDeviationCutoff.
 	StdDeviations float64
 
+	// Cutoff, if set, decides how many of the ranked
+	// results to keep. See CutoffStrategy. If set,
+	// StdDeviations is ignored.
+	Cutoff CutoffStrategy
+
 	// Limit is how many vectors max to return
 	Limit int
+
+	// MinScore, if set, drops any result scoring below it.
+	// It is only valid for similarity metrics (COSINE,
+	// DOT_PRODUCT), where higher scores are better.
+	MinScore *float64
+
+	// MaxDistance, if set, drops any result further than it
+	// from the target. It is only valid for distance
+	// metrics (EUCLIDEAN), where lower scores are better.
+	MaxDistance *float64
+
+	// Normalize, if true, sets the Relevance of each
+	// SearchResult - its score mapped to [0, 1] for the
+	// metric in use. See normalizeScore.
+	Normalize bool
+
+	// ScoreFunctions, if set, are applied in order to each
+	// result's similarity - typically to fold in metadata
+	// such as recency. Ordering, cutoffs, and thresholds
+	// all use the final combined score.
+	ScoreFunctions []ScoreFunction
+
+	// NormStatistics, if set, calibrates the normalization
+	// of DOT_PRODUCT scores by the collection's vector
+	// norms. See DB.NormStatistics.
+	NormStatistics *NormStatistics
+
+	// After is a search-after cursor; only results that
+	// rank after it are returned. Use the Cursor of the
+	// prior page's SearchResults to page through results.
+	After *SearchCursor
+
+	// Facets are metadata columns to count the distinct
+	// values of across the search. See Facet.
+	Facets []string
+
+	// GroupBy, if set, is a metadata column to group the
+	// results by; only the GroupSize best results for each
+	// distinct value are kept. Limit then limits the
+	// number of groups rather than results. Grouped
+	// searches can not be paged via After.
+	GroupBy string
+
+	// GroupSize is how many results to keep per group.
+	// If 0, one result per group is kept.
+	GroupSize int
+
+	// Reranker, if set, rescores the top RerankTop results
+	// that survive the cutoff as a second stage of ranking.
+	// Reranked searches can not be paged via After.
+	Reranker Reranker
+
+	// RerankTop is how many of the top results are given
+	// to the Reranker. If 0, all results are.
+	RerankTop int
+
+	// MMR, if set, reranks the results for diversity via
+	// Maximal Marginal Relevance. MMR reranked searches can
+	// not be paged via After.
+	MMR *MMROptions
+
+	// Matryoshka, if set, searches in two stages; first on a
+	// prefix of each vector, then on the full vectors of the
+	// best of those. It is only supported by Search and the
+	// searches built on it.
+	Matryoshka *MatryoshkaOptions
 }
 
 var DefaultFilterOptions FilterOptions = FilterOptions{
@@ -47,6 +171,49 @@ var DefaultFilterOptions FilterOptions = FilterOptions{
 	Limit:             0,
 }
 
+// QueryOptions control the ordering, paging, and projection
+// of Query results.
+type QueryOptions struct {
+	// OrderBy orders the results by the given metadata
+	// columns, in order of priority. Ties are always broken
+	// by rowid so that the ordering is stable.
+	OrderBy []OrderBy
+
+	// Limit is how many vectors max to return. 0 means
+	// there is no limit.
+	Limit int
+
+	// Offset is how many vectors to skip before returning
+	// results. Prefer After for deep paging; OFFSET still
+	// has SQLite walk over every skipped row.
+	Offset int
+
+	// After is a keyset cursor - only rows that come after
+	// it in the OrderBy ordering are returned. Use
+	// NextCursor to generate one from the last vector of
+	// the prior page.
+	After *QueryCursor
+
+	// Columns, if set, limits which columns are selected.
+	// If VECTOR_COLUMN_NAME is not included then the vector
+	// blob is never read or decoded. OrderBy columns are
+	// always selected so that cursors can be built.
+	Columns []string
+}
+
+type OrderBy struct {
+	Column     string
+	Descending bool
+}
+
+// QueryCursor marks a position in an ordered Query; Values
+// are the OrderBy column values of the last row seen, and
+// RowID is its rowid.
+type QueryCursor struct {
+	Values []interface{}
+	RowID  int64
+}
+
 type ColumnFilter struct {
 	Column    string
 	Operation string
@@ -54,6 +221,12 @@ type ColumnFilter struct {
 }
 
 func NewDB(db *sql.DB, schema *Schema, config *VectorConfig) *DB {
+	return newDB(db, schema, config, true)
+}
+
+// newDB sets up the DB, adding the default vector field's
+// column to the schema if requested
+func newDB(db *sql.DB, schema *Schema, config *VectorConfig, withVector bool) *DB {
 	// If the schema does not have a name set, we need to
 	// set one
 	if schema.Name == "" {
@@ -69,7 +242,7 @@ func NewDB(db *sql.DB, schema *Schema, config *VectorConfig) *DB {
 			break
 		}
 	}
-	if !vectorExists {
+	if withVector && !vectorExists {
 		schema.Columns = append(schema.Columns, &Column{
 			Name:       "vector",
 			Type:       "BLOB",
@@ -79,16 +252,55 @@ func NewDB(db *sql.DB, schema *Schema, config *VectorConfig) *DB {
 		})
 	}
 
+	// Likewise for the sparse vector column, if requested
+	if config != nil && config.Sparse {
+		sparseExists := false
+		for _, column := range schema.Columns {
+			if column.Name == SPARSE_VECTOR_COLUMN_NAME {
+				sparseExists = true
+				break
+			}
+		}
+		if !sparseExists {
+			schema.Columns = append(schema.Columns, &Column{
+				Name: SPARSE_VECTOR_COLUMN_NAME,
+				Type: "BLOB",
+			})
+		}
+	}
+
+	fields := map[string]*VectorConfig{}
+	if withVector {
+		fields[VECTOR_COLUMN_NAME] = config
+	}
+
 	return &DB{
-		db:     db,
-		schema: schema,
-		config: config,
+		db:           db,
+		schema:       schema,
+		config:       config,
+		fields:       fields,
+		vectorColumn: VECTOR_COLUMN_NAME,
 	}
 }
 
+// Schema returns the collection's schema, ie as discovered
+// when the table was adopted or opened from a Store
+func (db *DB) Schema() *Schema {
+	return db.schema
+}
+
 // Migrate will take the expected schema, and ensure that the
 // table is created or altered to represent the current schema
+//
+// The collection is then recorded in the registry; see Store.
+// If it is already registered, each registered vector field
+// must still be configured as it was, though fields may be
+// added.
 func (db *DB) Migrate() error {
+	if db.adopted {
+		return fmt.Errorf("table %s was adopted and can not be migrated", db.schema.Name)
+	}
+
 	// First check to see if we have a current schema for the
 	// table.
 	discoveredSchema, err := FromSQL(db.db, db.schema.Name)
@@ -96,12 +308,42 @@ func (db *DB) Migrate() error {
 		return err
 	}
 
+	// An entry for a table that no longer exists is stale,
+	// and is replaced
+	info, err := readRegistry(db.db, db.schema.Name)
+	if err != nil {
+		return err
+	}
+	createdAt := time.Now()
+	if info != nil && discoveredSchema != nil {
+		if err := validateMigration(info, db.fields); err != nil {
+			return err
+		}
+		createdAt = info.CreatedAt
+	}
+
 	if discoveredSchema == nil {
 		// We have no existing table, so just create it
-		return db.createTable()
+		err = db.createTable()
 	} else {
-		return db.alterTable(discoveredSchema)
+		err = db.alterTable(discoveredSchema)
+	}
+	if err != nil {
+		return err
 	}
+
+	if err := db.migrateFullText(); err != nil {
+		return err
+	}
+	if err := db.migrateSparse(); err != nil {
+		return err
+	}
+
+	schema, err := collectionSchema(db.db, db.schema.Name)
+	if err != nil {
+		return err
+	}
+	return writeRegistry(db.db, schema, db.fields, createdAt)
 }
 
 func (db *DB) createTable() error {
@@ -122,26 +364,42 @@ func (db *DB) createTable() error {
 	return nil
 }
 
-func (db *DB) alterTable(other *Schema) error {
-	queries := db.schema.AlterSchemaSQL(other)
-	for _, query := range queries {
-		_, err := db.db.Exec(query)
-		if err != nil {
-			return err
-		}
+// alterTable migrates the existing table, as described by
+// the discovered schema, to our schema. The table is renamed,
+// recreated, and copied across, so this is done within a
+// transaction lest a failure leave it half migrated.
+func (db *DB) alterTable(discovered *Schema) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	if err := execQueries(tx, discovered.AlterSchemaSQL(db.schema)); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (db *DB) validateInsert(vector *Vector) error {
 	// First we ensure that the vector length matches
-	// our expected vector length
-	if len(vector.Vector) != db.config.Length {
-		return fmt.Errorf(
-			"vector length %d does not match expected length %d",
-			len(vector.Vector),
-			db.config.Length,
-		)
+	// our expected vector length. Named fields may be
+	// left unset, and filled later via SetField.
+	if config, ok := db.fields[VECTOR_COLUMN_NAME]; ok {
+		if err := validateFieldVector(VECTOR_COLUMN_NAME, config, vector.Vector); err != nil {
+			return err
+		}
+	} else if len(vector.Vector) > 0 {
+		return fmt.Errorf("schema %s has no default vector field; set Fields instead", db.schema.Name)
+	}
+	for name, value := range vector.Fields {
+		config, ok := db.fields[name]
+		if !ok || name == VECTOR_COLUMN_NAME {
+			return fmt.Errorf("vector field %s does not exist", name)
+		}
+		if err := validateFieldVector(name, config, value); err != nil {
+			return err
+		}
 	}
 
 	// Ensure that that metadata:
@@ -174,6 +432,15 @@ func (db *DB) validateInsert(vector *Vector) error {
 		}
 	}
 
+	if vector.Sparse != nil {
+		if !db.config.Sparse {
+			return fmt.Errorf("schema %s does not store sparse vectors", db.schema.Name)
+		}
+		if err := vector.Sparse.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -194,8 +461,20 @@ func (db *DB) Insert(vector *Vector) error {
 		}
 		placeholders += "?"
 		columnNames += column.Name
-		if column.Name == "vector" {
-			values = append(values, vector.ToBytes())
+		if field, ok := db.columnField(column.Name); ok {
+			config := db.fields[field]
+			value := vector.Field(field)
+			if field != VECTOR_COLUMN_NAME && value == nil {
+				values = append(values, nil)
+			} else {
+				values = append(values, encodeVector(value, config.Encoding))
+			}
+		} else if column.Name == SPARSE_VECTOR_COLUMN_NAME && db.config.Sparse {
+			if vector.Sparse != nil {
+				values = append(values, vector.Sparse.ToBytes())
+			} else {
+				values = append(values, nil)
+			}
 		} else {
 			values = append(values, vector.Metadata[column.Name])
 		}
@@ -208,9 +487,35 @@ func (db *DB) Insert(vector *Vector) error {
 		placeholders,
 	)
 
-	// Execute the query
-	_, err = db.db.Exec(query, values...)
-	return err
+	// Execute the query; postings are written alongside
+	// the row so that they can't fall out of step
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, values...)
+	if err != nil {
+		return err
+	}
+
+	// Note the rowid so the vector can be identified later
+	rowID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if db.usePostings() {
+		if err := db.insertPostings(tx, rowID, vector.Sparse); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	vector.RowID = rowID
+	return nil
 }
 
 func (db *DB) validateQueryFilter(filter *Filter) error {
@@ -230,14 +535,43 @@ func (db *DB) validateQueryFilter(filter *Filter) error {
 	for _, column := range filter.Metadata {
 		if _, ok := allColumnNames[column.Column]; !ok {
 			return fmt.Errorf("column %s does not exist", column.Column)
-		} else if VECTOR_COLUMN_NAME == column.Column {
-			return fmt.Errorf("you can not specify %s in your query filter", VECTOR_COLUMN_NAME)
+		} else if db.isVectorColumn(column.Column) {
+			return fmt.Errorf("you can not specify %s in your query filter", column.Column)
+		}
+
+		// Ensure the operation is one we know how to
+		// build, and that IN operations have a set of
+		// values to work with
+		operation := strings.ToUpper(column.Operation)
+		if !validOperations[operation] {
+			return fmt.Errorf("operation %s is not supported", column.Operation)
+		}
+		if operation == OP_IN || operation == OP_NOT_IN {
+			if column.Value == nil || reflect.TypeOf(column.Value).Kind() != reflect.Slice {
+				return fmt.Errorf("operation %s on column %s requires a slice of values", operation, column.Column)
+			}
 		}
 	}
 
 	return nil
 }
 
+// validateKeyColumn ensures the column exists and holds
+// values that can be used as map keys when grouping or
+// counting results; BLOBs (and so the vector) can not.
+func (db *DB) validateKeyColumn(name string) error {
+	for _, column := range db.schema.Columns {
+		if column.Name != name {
+			continue
+		}
+		if db.isVectorColumn(name) || strings.Contains(strings.ToUpper(column.Type), "BLOB") {
+			return fmt.Errorf("column %s can not be used to group or count results", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("column %s does not exist", name)
+}
+
 func (db *DB) rowsToVectors(rows *sql.Rows) ([]*Vector, error) {
 	defer rows.Close()
 
@@ -270,10 +604,33 @@ func (db *DB) rowsToVectors(rows *sql.Rows) ([]*Vector, error) {
 		// Now we iterate through the results and assign
 		// their values to the vector
 		for index, column := range columns {
-			if column == VECTOR_COLUMN_NAME {
-				bytes := (values[index]).([]byte)
-				// bytes := (*(results[index].(*interface{}))).([]byte)
-				vector.FromBytes(bytes)
+			if field, ok := db.columnField(column); ok {
+				config := db.fields[field]
+				if values[index] == nil {
+					continue
+				}
+				bytes, ok := (values[index]).([]byte)
+				if !ok {
+					return nil, fmt.Errorf("vector field %s does not hold a BLOB", column)
+				}
+				value, err := decodeVector(bytes, config.Encoding)
+				if err != nil {
+					return nil, fmt.Errorf("vector field %s: %v", column, err)
+				}
+				vector.setField(field, value)
+			} else if column == SPARSE_VECTOR_COLUMN_NAME && db.config.Sparse {
+				if values[index] != nil {
+					sparse, err := SparseVectorFromBytes((values[index]).([]byte))
+					if err != nil {
+						return nil, err
+					}
+					vector.Sparse = sparse
+				}
+			} else if column == ROWID_COLUMN_NAME {
+				vector.RowID = (values[index]).(int64)
+			} else if values[index] == nil {
+				// NULL values can't be converted
+				vector.Metadata[column] = nil
 			} else {
 				// Attempt to conver to the correct type
 				// for easier use
@@ -293,13 +650,15 @@ func (db *DB) rowsToVectors(rows *sql.Rows) ([]*Vector, error) {
 							vector.Metadata[column] = (values[index]).([]byte)
 						case "TIMESTAMP":
 							vector.Metadata[column] = (values[index]).(time.Time)
+						default:
+							vector.Metadata[column] = values[index]
 						}
 						break
 					}
 				}
 				// Failsafe if type is unrecognized
 				if !matched {
-					vector.Metadata[column] = results[index]
+					vector.Metadata[column] = values[index]
 				}
 			}
 		}
@@ -316,49 +675,111 @@ func (db *DB) rowsToVectors(rows *sql.Rows) ([]*Vector, error) {
 // are recalling all vectors - not recommended but
 // possible for smaller datasets
 func (db *DB) Query(filter *Filter) ([]*Vector, error) {
+	return db.QueryWithOptions(filter, nil)
+}
+
+func (db *DB) validateQueryOptions(options *QueryOptions) error {
+	allColumnNames := map[string]bool{}
+	for _, column := range db.schema.Columns {
+		allColumnNames[column.Name] = true
+	}
+
+	for _, column := range options.Columns {
+		if !allColumnNames[column] {
+			return fmt.Errorf("column %s does not exist", column)
+		}
+	}
+
+	for _, order := range options.OrderBy {
+		if !allColumnNames[order.Column] {
+			return fmt.Errorf("column %s does not exist", order.Column)
+		} else if db.isVectorColumn(order.Column) {
+			return fmt.Errorf("you can not order by %s", order.Column)
+		}
+	}
+
+	if options.Limit < 0 || options.Offset < 0 {
+		return fmt.Errorf("limit and offset can not be negative")
+	}
+
+	if options.After != nil && len(options.After.Values) != len(options.OrderBy) {
+		return fmt.Errorf(
+			"cursor has %d values but there are %d order by columns",
+			len(options.After.Values),
+			len(options.OrderBy),
+		)
+	}
+
+	return nil
+}
+
+// QueryWithOptions is Query, but with control over the
+// ordering, paging, and selected columns of the results.
+// If options is nil it behaves exactly as Query.
+func (db *DB) QueryWithOptions(filter *Filter, options *QueryOptions) ([]*Vector, error) {
+	if options == nil {
+		options = &QueryOptions{}
+	}
 	if err := db.validateQueryFilter(filter); err != nil {
 		return nil, err
 	}
+	if err := db.validateQueryOptions(options); err != nil {
+		return nil, err
+	}
 
-	// Build our SELECT clause via the metadata columns
-	selectClause := ""
-	for index, column := range db.schema.Columns {
-		if index > 0 {
-			selectClause += ", "
+	// Build our SELECT clause via the metadata columns. If
+	// we're projecting, OrderBy columns are included too
+	columns := options.Columns
+	if len(columns) > 0 {
+		columns = append([]string{}, columns...)
+		for _, order := range options.OrderBy {
+			columns = append(columns, order.Column)
 		}
-		selectClause += column.Name
 	}
+	selectClause := db.selectClause(columns)
 
 	// Build up our query
-	var query string
-	whereValues := []interface{}{}
-	if filter != nil && filter.Metadata != nil && len(filter.Metadata) != 0 {
-		// Build our WHERE statement via the filter
-		whereClause := ""
-		for index, column := range filter.Metadata {
-			if index > 0 {
-				whereClause += " AND "
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s ",
+		selectClause,
+		db.schema.Name,
+	)
+	whereClause, whereValues := buildWhereClause(filter)
+	if options.After != nil {
+		keysetClause, keysetValues := buildKeysetClause(options.OrderBy, options.After)
+		if whereClause != "" {
+			whereClause = fmt.Sprintf("(%s) AND (%s)", whereClause, keysetClause)
+		} else {
+			whereClause = keysetClause
+		}
+		whereValues = append(whereValues, keysetValues...)
+	}
+	if whereClause != "" {
+		query += fmt.Sprintf("WHERE %s ", whereClause)
+	}
+
+	if len(options.OrderBy) > 0 || options.After != nil {
+		orderClause := ""
+		for _, order := range options.OrderBy {
+			orderClause += order.Column
+			if order.Descending {
+				orderClause += " DESC"
 			}
-			whereClause += fmt.Sprintf(
-				"%s %s ?",
-				column.Column,
-				column.Operation,
-			)
-			whereValues = append(whereValues, column.Value)
+			orderClause += ", "
 		}
+		orderClause += ROWID_COLUMN_NAME
+		query += fmt.Sprintf("ORDER BY %s ", orderClause)
+	}
 
-		query = fmt.Sprintf(
-			"SELECT %s FROM %s WHERE %s ",
-			selectClause,
-			db.schema.Name,
-			whereClause,
-		)
-	} else {
-		query = fmt.Sprintf(
-			"SELECT %s FROM %s ",
-			selectClause,
-			db.schema.Name,
-		)
+	// SQLite requires a LIMIT to use an OFFSET; -1 is
+	// treated as no limit
+	if options.Limit > 0 {
+		query += fmt.Sprintf("LIMIT %d ", options.Limit)
+	} else if options.Offset > 0 {
+		query += "LIMIT -1 "
+	}
+	if options.Offset > 0 {
+		query += fmt.Sprintf("OFFSET %d ", options.Offset)
 	}
 
 	// Execute the query and build our search base
@@ -370,84 +791,195 @@ func (db *DB) Query(filter *Filter) ([]*Vector, error) {
 	return db.rowsToVectors(rows)
 }
 
-func (db *DB) QuerySimilarity(target *Vector, filter *Filter, options *FilterOptions) ([]*Vector, []float64, error) {
-	if options == nil {
-		options = &DefaultFilterOptions
+// selectClause builds the SELECT clause for the given
+// columns, or all columns if none are given. We always grab
+// the rowid to identify the vector.
+func (db *DB) selectClause(columns []string) string {
+	selectedColumns := map[string]bool{}
+	for _, column := range columns {
+		selectedColumns[column] = true
 	}
 
-	// First we get the vectors that match the filter
-	vectors, err := db.Query(filter)
-	if err != nil {
-		return nil, nil, err
+	selectClause := fmt.Sprintf("rowid AS %s", ROWID_COLUMN_NAME)
+	for _, column := range db.schema.Columns {
+		if len(columns) > 0 && !selectedColumns[column.Name] {
+			continue
+		}
+		selectClause += ", " + column.Name
 	}
 
-	// Then we calculate the similarity to the target vector
-	similarities, err := target.SimilarityToVectorSet(vectors, options.SimilarityOptions)
-	if err != nil {
-		return nil, nil, err
+	return selectClause
+}
+
+// ROWID_BATCH_SIZE is the most rowids looked up per query,
+// keeping well within SQLite's limit on bound parameters
+const ROWID_BATCH_SIZE = 500
+
+// GetByRowID returns the vectors with the given rowids, in
+// the order requested. If any rowid does not exist an error
+// is returned.
+func (db *DB) GetByRowID(rowIDs ...int64) ([]*Vector, error) {
+	vectorsByRowID := map[int64]*Vector{}
+	for start := 0; start < len(rowIDs); start += ROWID_BATCH_SIZE {
+		end := start + ROWID_BATCH_SIZE
+		if end > len(rowIDs) {
+			end = len(rowIDs)
+		}
+
+		placeholders := ""
+		values := []interface{}{}
+		for index, rowID := range rowIDs[start:end] {
+			if index > 0 {
+				placeholders += ", "
+			}
+			placeholders += "?"
+			values = append(values, rowID)
+		}
+
+		query := fmt.Sprintf(
+			"SELECT %s FROM %s WHERE rowid IN (%s)",
+			db.selectClause(nil),
+			db.schema.Name,
+			placeholders,
+		)
+		rows, err := db.db.Query(query, values...)
+		if err != nil {
+			return nil, err
+		}
+		found, err := db.rowsToVectors(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, vector := range found {
+			vectorsByRowID[vector.RowID] = vector
+		}
 	}
 
-	// Then we sort the vectors by their distance
-	// to the target vector. We need to create a map
-	// to track association between similarity scores
-	// and the associated vector
-	sortMap := map[*Vector]float64{}
-	for index, vector := range vectors {
-		sortMap[vector] = similarities[index]
+	vectors := make([]*Vector, len(rowIDs))
+	for index, rowID := range rowIDs {
+		vector, ok := vectorsByRowID[rowID]
+		if !ok {
+			return nil, fmt.Errorf("rowid %d does not exist", rowID)
+		}
+		vectors[index] = vector
 	}
 
-	sort.Slice(vectors, func(a int, b int) bool {
-		return sortMap[vectors[a]] > sortMap[vectors[b]]
-	})
+	return vectors, nil
+}
 
-	// Similarly, we want the similarity scores to be
-	// sorted; since we have the sortMap, we don't
-	// need to worry about sorting again
-	sortedSimilarities := make([]float64, len(similarities))
-	for index, vector := range vectors {
-		sortedSimilarities[index] = sortMap[vector]
+// NextCursor builds the cursor pointing just past the given
+// vector - typically the last vector of a page - for use
+// as QueryOptions.After on the next query.
+func (o *QueryOptions) NextCursor(vector *Vector) *QueryCursor {
+	cursor := &QueryCursor{
+		Values: []interface{}{},
+		RowID:  vector.RowID,
+	}
+	for _, order := range o.OrderBy {
+		cursor.Values = append(cursor.Values, vector.Metadata[order.Column])
 	}
+	return cursor
+}
 
-	// If the std dev is not 0, we need to find outliers
-	// and filter
-	if options.StdDeviations > 0 {
-		mean, stdDev := meanAndStandardDeviation(sortedSimilarities)
-		outlier := mean + (options.StdDeviations * stdDev)
+// buildKeysetClause generates the conditions selecting rows
+// that come after the cursor in the given ordering. For
+// ordering (a, b) this looks like:
+//
+//	(a > ?) OR (a IS ? AND b > ?) OR (a IS ? AND b IS ? AND rowid > ?)
+//
+// ...with the comparison flipped for descending columns. IS
+// is used so that NULL cursor values match, and as SQLite
+// sorts NULLs first, NULLs come after every value of a
+// descending column and before every value of an ascending
+// one.
+func buildKeysetClause(orderBy []OrderBy, cursor *QueryCursor) (string, []interface{}) {
+	clause := ""
+	values := []interface{}{}
 
-		// Find the index of the first non outlier
-		cutoffIndex := 0
-		for index, similarity := range sortedSimilarities {
-			if similarity < outlier {
-				cutoffIndex = index
-				break
+	for index := 0; index <= len(orderBy); index++ {
+		if index > 0 {
+			clause += " OR "
+		}
+		clause += "("
+
+		// All prior columns are equal to the cursor...
+		for prior := 0; prior < index; prior++ {
+			clause += fmt.Sprintf("%s IS ? AND ", orderBy[prior].Column)
+			values = append(values, cursor.Values[prior])
+		}
+
+		// ...and this column is past it. The rowid is the
+		// final tie breaker and is always ascending.
+		if index < len(orderBy) {
+			column := orderBy[index].Column
+			value := cursor.Values[index]
+			switch {
+			case value == nil && orderBy[index].Descending:
+				// Nothing sorts after NULL
+				clause += "0"
+			case value == nil:
+				clause += fmt.Sprintf("%s IS NOT NULL", column)
+			case orderBy[index].Descending:
+				clause += fmt.Sprintf("(%s %s ? OR %s IS NULL)", column, OP_LESS, column)
+				values = append(values, value)
+			default:
+				clause += fmt.Sprintf("%s %s ?", column, OP_GREATER)
+				values = append(values, value)
 			}
+		} else {
+			clause += fmt.Sprintf("%s > ?", ROWID_COLUMN_NAME)
+			values = append(values, cursor.RowID)
 		}
 
-		// Filter out non-outliers
-		vectors = vectors[0:cutoffIndex]
-		sortedSimilarities = sortedSimilarities[0:cutoffIndex]
+		clause += ")"
 	}
 
-	// If the limit is 0, we can return now
-	if options.Limit == 0 {
-		return vectors, sortedSimilarities, nil
-	} else {
-		return vectors[0:options.Limit], sortedSimilarities[0:options.Limit], nil
-	}
+	return clause, values
 }
 
-func meanAndStandardDeviation(values []float64) (float64, float64) {
-	mean := 0.0
-	for _, value := range values {
-		mean += value
+// buildWhereClause generates the conditions (without the
+// WHERE keyword) and their placeholder values for a given
+// filter. If there is nothing to filter on, an empty string
+// is returned. The filter is assumed to have been validated.
+func buildWhereClause(filter *Filter) (string, []interface{}) {
+	whereClause := ""
+	whereValues := []interface{}{}
+	if filter == nil || len(filter.Metadata) == 0 {
+		return whereClause, whereValues
 	}
-	mean /= float64(len(values))
 
-	variance := 0.0
-	for _, value := range values {
-		variance += math.Pow(value-mean, 2)
+	for index, column := range filter.Metadata {
+		if index > 0 {
+			whereClause += " AND "
+		}
+
+		operation := strings.ToUpper(column.Operation)
+		if operation == OP_IN || operation == OP_NOT_IN {
+			// Each value in the set gets its own placeholder
+			values := reflect.ValueOf(column.Value)
+			placeholders := ""
+			for i := 0; i < values.Len(); i++ {
+				if i > 0 {
+					placeholders += ", "
+				}
+				placeholders += "?"
+				whereValues = append(whereValues, values.Index(i).Interface())
+			}
+			whereClause += fmt.Sprintf(
+				"%s %s (%s)",
+				column.Column,
+				operation,
+				placeholders,
+			)
+		} else {
+			whereClause += fmt.Sprintf(
+				"%s %s ?",
+				column.Column,
+				operation,
+			)
+			whereValues = append(whereValues, column.Value)
+		}
 	}
-	variance /= float64(len(values))
 
-	return mean, math.Sqrt(variance)
+	return whereClause, whereValues
 }
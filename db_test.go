@@ -3,13 +3,13 @@ package gsvt
 import (
 	"database/sql"
 	"encoding/csv"
-	"fmt"
 	"math/rand"
 	"os"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/drewlanenga/govector"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -427,34 +427,277 @@ func TestAlterTableMigration(t *testing.T) {
 
 	// Now we have a database with a set of vectors in
 	// it; let's migrate with a removed and added
-	// column by editing our existing schema.
-	fmt.Println(db.schema)
+	// column by editing a copy of our existing schema.
+	// Deleting a row first leaves a gap in the rowids that
+	// the migration must preserve.
+	_, err = sqlite.Exec("DELETE FROM " + db.schema.Name + " WHERE rowid = 1")
+	require.Nil(t, err)
+	before, err := db.Query(nil)
+	require.Nil(t, err)
+
 	changedSchema := *db.schema
+	changedSchema.Columns = []*Column{}
+	for _, column := range db.schema.Columns {
+		if column.Name != "source" {
+			changedSchema.Columns = append(changedSchema.Columns, column)
+		}
+	}
 	changedSchema.Columns = append(changedSchema.Columns, &Column{
 		Name: "new_column",
-		Type: "string",
+		Type: "TEXT",
 	})
-
-	changedSchema.Columns = append(changedSchema.Columns[:2], changedSchema.Columns[3:]...)
-	fmt.Println("????")
-	fmt.Println(db.schema)
-	fmt.Println(&changedSchema)
-	fmt.Println("????")
-	err = db.Migrate()
+	changed := NewDB(sqlite, &changedSchema, db.config)
+	err = changed.Migrate()
 	require.Nil(t, err)
 
 	// Now read back the schema and ensure that it is
-	// what we expect
+	// what we expect - the table was migrated to the new
+	// schema, not back to the old one
 	schema, err := FromSQL(db.db, db.schema.Name)
 	require.Nil(t, err)
 	require.NotNil(t, schema)
 
 	assert.True(t, changedSchema.Equal(schema))
 
+	// ...and that the temporary table was dropped
+	var tables int
+	err = sqlite.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		db.schema.Name+"_tmp",
+	).Scan(&tables)
+	require.Nil(t, err)
+	assert.Equal(t, 0, tables)
+
 	// We then have to ensure that the data is still
-	// there, minus our source column
+	// there, minus our source column, and that every
+	// vector kept its rowid
+	after, err := changed.Query(nil)
+	require.Nil(t, err)
+	require.Len(t, after, len(before))
+	for index, vector := range after {
+		expected := before[index]
+		assert.Equal(t, expected.RowID, vector.RowID)
+		assert.Equal(t, expected.Vector, vector.Vector)
+		assert.Equal(t, expected.Metadata["user"], vector.Metadata["user"])
+		assert.Equal(t, expected.Metadata["text"], vector.Metadata["text"])
+		assert.NotContains(t, vector.Metadata, "source")
+		assert.Nil(t, vector.Metadata["new_column"])
+	}
+}
+
+func TestAlterTableMigrationRollback(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Setup our db and vectors
+	db, _, _, err := setupVectorsAndDB(sqlite)
+	require.Nil(t, err)
+	before, err := db.Query(nil)
+	require.Nil(t, err)
+
+	// A required column with no default can't be filled
+	// for the existing rows, so the copy fails midway
+	changedSchema := *db.schema
+	changedSchema.Columns = append([]*Column{}, db.schema.Columns...)
+	changedSchema.Columns = append(changedSchema.Columns, &Column{
+		Name:     "new_column",
+		Type:     "TEXT",
+		Required: true,
+	})
+	changed := NewDB(sqlite, &changedSchema, db.config)
+	assert.NotNil(t, changed.Migrate())
+
+	// The table is left as it was, with no temporary table
+	schema, err := FromSQL(sqlite, db.schema.Name)
+	require.Nil(t, err)
+	require.NotNil(t, schema)
+	assert.True(t, db.schema.Equal(schema))
+
+	var tables int
+	err = sqlite.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		db.schema.Name+"_tmp",
+	).Scan(&tables)
+	require.Nil(t, err)
+	assert.Equal(t, 0, tables)
+
+	after, err := db.Query(nil)
+	require.Nil(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestQueryWithOptions(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Setup our db and vectors
+	db, vectors, _, err := setupVectorsAndDB(sqlite)
+	require.Nil(t, err)
+
+	// Inserting should have assigned each vector a rowid
+	for _, vector := range vectors {
+		assert.NotZero(t, vector.RowID)
+	}
+
+	// Order by our timestamp, newest first, and ensure
+	// that the results come back in that order
+	options := &QueryOptions{
+		OrderBy: []OrderBy{
+			{Column: "created_at", Descending: true},
+		},
+	}
+	ordered, err := db.QueryWithOptions(nil, options)
+	require.Nil(t, err)
+	require.Len(t, ordered, len(vectors))
+	for index := 1; index < len(ordered); index++ {
+		previous := ordered[index-1].Metadata["created_at"].(time.Time)
+		current := ordered[index].Metadata["created_at"].(time.Time)
+		assert.False(t, current.After(previous))
+	}
+
+	// Limit and offset should slice that same ordering
+	options.Limit = 5
+	options.Offset = 3
+	page, err := db.QueryWithOptions(nil, options)
+	require.Nil(t, err)
+	require.Len(t, page, 5)
+	for index, vector := range page {
+		assert.Equal(t, ordered[index+3].RowID, vector.RowID)
+	}
+
+	// Page through with the keyset cursor; we should see
+	// every vector exactly once, in order
+	options.Offset = 0
+	options.After = nil
+	seen := []*Vector{}
+	for {
+		page, err := db.QueryWithOptions(nil, options)
+		require.Nil(t, err)
+		if len(page) == 0 {
+			break
+		}
+		seen = append(seen, page...)
+		options.After = options.NextCursor(page[len(page)-1])
+	}
+	require.Len(t, seen, len(ordered))
+	for index, vector := range seen {
+		assert.Equal(t, ordered[index].RowID, vector.RowID)
+	}
+
+	// Cursors should combine with filters as well
+	filter := &Filter{
+		Metadata: []ColumnFilter{
+			{
+				Column:    "user",
+				Operation: "==",
+				Value:     "user1",
+			},
+		},
+	}
+	options.After = nil
+	filtered := []*Vector{}
+	for {
+		page, err := db.QueryWithOptions(filter, options)
+		require.Nil(t, err)
+		if len(page) == 0 {
+			break
+		}
+		filtered = append(filtered, page...)
+		options.After = options.NextCursor(page[len(page)-1])
+	}
+	assert.Len(t, filtered, len(roughQuery(db, vectors, filter)))
+
+	// Project only a single column; the vector should
+	// not be loaded at all
+	projected, err := db.QueryWithOptions(nil, &QueryOptions{
+		Columns: []string{"text"},
+	})
+	require.Nil(t, err)
+	require.Len(t, projected, len(vectors))
+	for _, vector := range projected {
+		assert.NotZero(t, vector.RowID)
+		assert.Len(t, vector.Vector, 0)
+		assert.Len(t, vector.Metadata, 1)
+		assert.Contains(t, vector.Metadata, "text")
+	}
+
+	// Bad options are rejected
+	_, err = db.QueryWithOptions(nil, &QueryOptions{
+		Columns: []string{"fake"},
+	})
+	assert.NotNil(t, err)
+	_, err = db.QueryWithOptions(nil, &QueryOptions{
+		OrderBy: []OrderBy{{Column: VECTOR_COLUMN_NAME}},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestQueryCursorNulls(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db, err := setupSmallDB(sqlite, []*Vector{
+		{Vector: govector.Vector{1, 0, 0}, Metadata: map[string]interface{}{"category": "b"}},
+		{Vector: govector.Vector{0, 1, 0}},
+		{Vector: govector.Vector{0, 0, 1}, Metadata: map[string]interface{}{"category": "a"}},
+		{Vector: govector.Vector{1, 1, 0}},
+	})
+	require.Nil(t, err)
+
+	// SQLite sorts NULLs first; paging a row at a time must
+	// walk over them in either direction
+	for _, descending := range []bool{false, true} {
+		options := &QueryOptions{
+			OrderBy: []OrderBy{{Column: "category", Descending: descending}},
+			Limit:   1,
+		}
+		seen := []int64{}
+		for {
+			page, err := db.QueryWithOptions(nil, options)
+			require.Nil(t, err)
+			if len(page) == 0 {
+				break
+			}
+			seen = append(seen, page[0].RowID)
+			options.After = options.NextCursor(page[0])
+		}
+
+		if descending {
+			assert.Equal(t, []int64{1, 3, 2, 4}, seen)
+		} else {
+			assert.Equal(t, []int64{2, 4, 3, 1}, seen)
+		}
+	}
+}
+
+func TestGetByRowID(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Setup our db and vectors
+	db, vectors, _, err := setupVectorsAndDB(sqlite)
+	require.Nil(t, err)
+
+	// Request a few out of order; they should come back
+	// in the order requested
+	found, err := db.GetByRowID(vectors[5].RowID, vectors[1].RowID, vectors[3].RowID)
+	require.Nil(t, err)
+	require.Len(t, found, 3)
+	for index, expected := range []*Vector{vectors[5], vectors[1], vectors[3]} {
+		assert.Equal(t, expected.RowID, found[index].RowID)
+		assert.Equal(t, expected.Metadata["text"], found[index].Metadata["text"])
+		assert.Len(t, found[index].Vector, len(expected.Vector))
+	}
 
-	// Spot check the users (user1, user2, user3)
-	// which should match the initial vectors
-	// on roughQuery still
+	// Unknown rowids are an error
+	_, err = db.GetByRowID(vectors[0].RowID, -1)
+	assert.NotNil(t, err)
 }
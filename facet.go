@@ -0,0 +1,40 @@
+package gsvt
+
+// Facet counts the distinct values of a metadata column
+// across a similarity search. Values are keyed as they are
+// found in Vector.Metadata; NULLs are counted under nil.
+type Facet struct {
+	Column string
+
+	// Results counts the values across the results that
+	// were returned - after any cutoff, cursor, and limit
+	// were applied.
+	Results map[interface{}]int
+
+	// Candidates counts the values across every vector
+	// that matched the filter, prior to any cutoff.
+	Candidates map[interface{}]int
+}
+
+func newFacets(columns []string) map[string]*Facet {
+	facets := map[string]*Facet{}
+	for _, column := range columns {
+		facets[column] = &Facet{
+			Column:     column,
+			Results:    map[interface{}]int{},
+			Candidates: map[interface{}]int{},
+		}
+	}
+	return facets
+}
+
+// validateFacets ensures each facet column can be used to
+// key the counts
+func (db *DB) validateFacets(columns []string) error {
+	for _, column := range columns {
+		if err := db.validateKeyColumn(column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchFacets(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}, Metadata: map[string]interface{}{"category": "a"}},
+		{Vector: govector.Vector{0.9, 0.1, 0.0}, Metadata: map[string]interface{}{"category": "b"}},
+		{Vector: govector.Vector{0.8, 0.2, 0.0}, Metadata: map[string]interface{}{"category": "a"}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}, Metadata: map[string]interface{}{"category": "b"}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}, Metadata: map[string]interface{}{"category": "c"}},
+		{Vector: govector.Vector{0.0, 0.1, 1.0}, Metadata: map[string]interface{}{}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Limit:             3,
+		Facets:            []string{"category"},
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+
+	facet, ok := results.Facets["category"]
+	require.True(t, ok)
+	assert.Equal(t, "category", facet.Column)
+
+	// The top 3 are a, b, a
+	assert.Equal(t, map[interface{}]int{"a": 2, "b": 1}, facet.Results)
+
+	// ...while the candidates are everything
+	assert.Equal(t, map[interface{}]int{"a": 2, "b": 2, "c": 1, nil: 1}, facet.Candidates)
+
+	// Facets on unknown or vector columns are rejected
+	_, err = db.Search(target, nil, &FilterOptions{
+		Facets: []string{"fake"},
+	})
+	assert.NotNil(t, err)
+	_, err = db.Search(target, nil, &FilterOptions{
+		Facets: []string{VECTOR_COLUMN_NAME},
+	})
+	assert.NotNil(t, err)
+}
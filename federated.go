@@ -0,0 +1,178 @@
+package gsvt
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// SearchCollection is one of the collections searched by a
+// FederatedSearch.
+type SearchCollection struct {
+	// Name labels the collection's results. If empty, the
+	// name of its table is used.
+	Name string
+
+	DB *DB
+
+	// Filter, if set, filters the collection's vectors. Each
+	// collection has its own, as their columns may differ.
+	Filter *Filter
+}
+
+func (c *SearchCollection) name() string {
+	if c.Name == "" {
+		return c.DB.schema.Name
+	}
+	return c.Name
+}
+
+// validateFederated ensures the collections can be searched,
+// and their scores compared, as one. Each must search a field
+// of the same length and metric, and of the same model if
+// both name one.
+func validateFederated(collections []*SearchCollection, options *FilterOptions) error {
+	if len(collections) == 0 {
+		return fmt.Errorf("federated search requires at least one collection")
+	}
+	if options.After != nil {
+		return fmt.Errorf("federated searches can not be paged via After")
+	}
+	if options.GroupBy != "" {
+		return fmt.Errorf("federated searches can not be grouped")
+	}
+	if options.Reranker != nil || options.MMR != nil {
+		return fmt.Errorf("federated searches are merged by score, and so can not be reranked")
+	}
+
+	names := map[string]bool{}
+	var first *VectorConfig
+	var firstOptions *FilterOptions
+	for _, collection := range collections {
+		if collection.DB == nil {
+			return fmt.Errorf("collection %s has no DB", collection.Name)
+		}
+		name := collection.name()
+		if names[name] {
+			return fmt.Errorf("collection %s is given more than once", name)
+		}
+		names[name] = true
+
+		resolved, config, err := collection.DB.fieldOptions(options)
+		if err != nil {
+			return fmt.Errorf("collection %s: %v", name, err)
+		}
+		if first == nil {
+			first, firstOptions = config, resolved
+			continue
+		}
+
+		firstName := collections[0].name()
+		if config.Length != first.Length || config.MultiVector != first.MultiVector {
+			return fmt.Errorf("collection %s has a different vector length to %s", name, firstName)
+		}
+		if resolved.similarityOptions().Method != firstOptions.similarityOptions().Method {
+			return fmt.Errorf("collection %s uses a different metric to %s", name, firstName)
+		}
+		if config.Model != "" && first.Model != "" && config.Model != first.Model {
+			return fmt.Errorf(
+				"collection %s holds %s embeddings, but %s holds %s",
+				name,
+				config.Model,
+				firstName,
+				first.Model,
+			)
+		}
+	}
+
+	return nil
+}
+
+// FederatedSearch searches several collections - which may
+// each be over their own connection, ie separate SQLite files
+// - for the target at once, merging their results into one
+// ranking. Each result is labelled with its Collection.
+//
+// Every collection is searched with the options, so cutoffs
+// and thresholds apply within each collection; Limit then
+// limits the merged results, ranked as the collections'
+// shared metric ranks them. Facet candidates are summed
+// across collections. Federated searches can't be paged,
+// grouped, or reranked.
+func FederatedSearch(target *Vector, collections []*SearchCollection, options *FilterOptions) (*SearchResults, error) {
+	if options == nil {
+		options = &DefaultFilterOptions
+	}
+	if err := validateFederated(collections, options); err != nil {
+		return nil, err
+	}
+	resolved, _, err := collections[0].DB.fieldOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	distance := isDistanceMetric(resolved.similarityOptions().Method)
+
+	// Each collection is searched concurrently
+	collectionResults := make([]*SearchResults, len(collections))
+	var group errgroup.Group
+	for index, collection := range collections {
+		index, collection := index, collection
+		// Each search has its own copy of the options, as
+		// searches resolve them as they go
+		collectionOptions := *options
+		if options.SimilarityOptions != nil {
+			similarityOptions := *options.SimilarityOptions
+			collectionOptions.SimilarityOptions = &similarityOptions
+		}
+		group.Go(func() error {
+			results, err := collection.DB.Search(target, collection.Filter, &collectionOptions)
+			if err != nil {
+				return fmt.Errorf("collection %s: %v", collection.name(), err)
+			}
+			collectionResults[index] = results
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Merge the results; each collection's are already
+	// ranked, so a stable sort keeps their tie breaks and
+	// otherwise favours the earlier collection
+	facets := newFacets(options.Facets)
+	results := []*SearchResult{}
+	for index, collection := range collections {
+		for _, result := range collectionResults[index].Results {
+			result.Collection = collection.name()
+			results = append(results, result)
+		}
+		for column, facet := range collectionResults[index].Facets {
+			for value, count := range facet.Candidates {
+				facets[column].Candidates[value] += count
+			}
+		}
+	}
+	sort.SliceStable(results, func(a int, b int) bool {
+		if results[a].Score == results[b].Score {
+			return false
+		}
+		return (results[a].Score > results[b].Score) != distance
+	})
+	if options.Limit > 0 && len(results) > options.Limit {
+		results = results[:options.Limit]
+	}
+
+	for _, result := range results {
+		for _, facet := range facets {
+			facet.Results[result.Vector.Metadata[facet.Column]]++
+		}
+	}
+
+	return &SearchResults{
+		Results:  results,
+		Facets:   facets,
+		Distance: distance,
+	}, nil
+}
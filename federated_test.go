@@ -0,0 +1,126 @@
+package gsvt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederatedSearch(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Two collections share a file, and a third has its own
+	store := NewStore(sqlite)
+	other, err := OpenStore("sqlite3", filepath.Join(t.TempDir(), "other.db"))
+	require.Nil(t, err)
+	defer other.Close()
+
+	config := &VectorConfig{Length: 3, Model: "text-embedding-small"}
+	insert := func(db *DB, category string, vectors ...govector.Vector) {
+		for _, vector := range vectors {
+			require.Nil(t, db.Insert(&Vector{
+				Vector:   vector,
+				Metadata: map[string]interface{}{"category": category},
+			}))
+		}
+	}
+
+	articles, err := store.Create(storeSchema("articles"), config)
+	require.Nil(t, err)
+	insert(articles, "news", govector.Vector{1, 0, 0}, govector.Vector{0, 1, 0})
+	comments, err := store.Create(storeSchema("comments"), config)
+	require.Nil(t, err)
+	insert(comments, "chat", govector.Vector{0.9, 0.1, 0}, govector.Vector{0, 0, 1})
+	archive, err := other.Create(storeSchema("articles"), config)
+	require.Nil(t, err)
+	insert(archive, "news", govector.Vector{0.8, 0.2, 0})
+
+	collections := []*SearchCollection{
+		{DB: articles},
+		{DB: comments},
+		{Name: "archive", DB: archive},
+	}
+	target := &Vector{Vector: govector.Vector{1, 0, 0}}
+	options := &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Limit:             3,
+		Facets:            []string{"category"},
+	}
+
+	results, err := FederatedSearch(target, collections, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+	labels := []string{}
+	for _, result := range results.Results {
+		labels = append(labels, result.Collection)
+	}
+	assert.Equal(t, []string{"articles", "comments", "archive"}, labels)
+	assert.Equal(t, 1.0, results.Results[0].Score)
+	assert.Equal(t, int64(1), results.Results[1].Vector.RowID)
+	assert.Equal(t, map[interface{}]int{"news": 3, "chat": 2}, results.Facets["category"].Candidates)
+	assert.Equal(t, map[interface{}]int{"news": 2, "chat": 1}, results.Facets["category"].Results)
+
+	// Each collection has its own filter
+	collections[0].Filter, err = Where("category").Eq("none").Build(articles)
+	require.Nil(t, err)
+	results, err = FederatedSearch(target, collections, options)
+	require.Nil(t, err)
+	assert.Equal(t, "comments", results.Results[0].Collection)
+	collections[0].Filter = nil
+
+	// The options are left as given, however many searches
+	// share them
+	shared := &SimilarityOptions{}
+	_, err = FederatedSearch(target, collections, &FilterOptions{SimilarityOptions: shared})
+	require.Nil(t, err)
+	assert.Equal(t, 0, shared.Workers)
+
+	// Distances merge nearest first
+	results, err = FederatedSearch(target, collections, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		Limit:             3,
+	})
+	require.Nil(t, err)
+	labels = []string{}
+	for _, result := range results.Results {
+		labels = append(labels, result.Collection)
+	}
+	assert.Equal(t, []string{"articles", "comments", "archive"}, labels)
+	assert.Equal(t, 0.0, results.Results[0].Score)
+
+	// Collections must be comparable
+	dotProduct := DOT_PRODUCT
+	for _, incompatible := range []*VectorConfig{
+		{Length: 4, Model: "text-embedding-small"},
+		{Length: 3, Method: &dotProduct},
+		{Length: 3, Model: "text-embedding-large"},
+	} {
+		name := "incompatible"
+		_, err = sqlite.Exec("DROP TABLE IF EXISTS " + name)
+		require.Nil(t, err)
+		db := NewDB(sqlite, &Schema{Name: name}, incompatible)
+		require.Nil(t, db.Migrate())
+
+		_, err = FederatedSearch(target, []*SearchCollection{{DB: articles}, {DB: db}}, options)
+		assert.NotNil(t, err)
+	}
+
+	for _, invalid := range [][]*SearchCollection{
+		{},
+		{{DB: articles}, {DB: articles}},
+		{{Name: "empty"}},
+	} {
+		_, err = FederatedSearch(target, invalid, options)
+		assert.NotNil(t, err)
+	}
+	_, err = FederatedSearch(target, collections, &FilterOptions{After: &SearchCursor{}})
+	assert.NotNil(t, err)
+	_, err = FederatedSearch(target, collections, &FilterOptions{GroupBy: "category"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,327 @@
+package gsvt
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/drewlanenga/govector"
+)
+
+// Encodings a vector field can be stored in
+const ENCODING_FLOAT64 = 0
+const ENCODING_FLOAT32 = 1
+
+// encodingSize is the number of bytes each value of the
+// encoding takes
+func encodingSize(encoding int) (int, error) {
+	switch encoding {
+	case ENCODING_FLOAT64:
+		return 8, nil
+	case ENCODING_FLOAT32:
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("unknown vector encoding %d", encoding)
+	}
+}
+
+// encodeVector encodes the vector as little endian values of
+// the given encoding. The encoding is assumed to be valid.
+func encodeVector(vector govector.Vector, encoding int) []byte {
+	if encoding == ENCODING_FLOAT64 {
+		return (&Vector{Vector: vector}).ToBytes()
+	}
+
+	byteArray := make([]byte, len(vector)*4)
+	for index, value := range vector {
+		start := index * 4
+		binary.LittleEndian.PutUint32(byteArray[start:start+4], math.Float32bits(float32(value)))
+	}
+	return byteArray
+}
+
+// decodeVector decodes a vector encoded by encodeVector
+func decodeVector(bytes []byte, encoding int) (govector.Vector, error) {
+	size, err := encodingSize(encoding)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes)%size != 0 {
+		return nil, fmt.Errorf("vector of %d bytes is not a multiple of %d", len(bytes), size)
+	}
+
+	if encoding == ENCODING_FLOAT64 {
+		vector := &Vector{}
+		vector.FromBytes(bytes)
+		return vector.Vector, nil
+	}
+
+	vector := make(govector.Vector, len(bytes)/4)
+	for index := range vector {
+		start := index * 4
+		vector[index] = float64(math.Float32frombits(binary.LittleEndian.Uint32(bytes[start : start+4])))
+	}
+	return vector, nil
+}
+
+// Field returns the value of the named vector field; the
+// default VECTOR_COLUMN_NAME field (or "") is Vector, and any
+// other is held within Fields.
+func (v *Vector) Field(name string) govector.Vector {
+	if name == "" || name == VECTOR_COLUMN_NAME {
+		return v.Vector
+	}
+	return v.Fields[name]
+}
+
+func (v *Vector) setField(name string, value govector.Vector) {
+	if name == VECTOR_COLUMN_NAME {
+		v.Vector = value
+		return
+	}
+	if v.Fields == nil {
+		v.Fields = map[string]govector.Vector{}
+	}
+	v.Fields[name] = value
+}
+
+// NewDBWithFields is NewDB for a collection with several
+// named vector fields, ie a title and a body embedding. Each
+// field is stored in a BLOB column of the same name as per
+// its own VectorConfig. The default VECTOR_COLUMN_NAME field
+// is only created if it is one of the fields given; if so
+// collection wide options, such as Sparse, are taken from its
+// config.
+func NewDBWithFields(db *sql.DB, schema *Schema, fields map[string]*VectorConfig) *DB {
+	config, ok := fields[VECTOR_COLUMN_NAME]
+	if !ok {
+		config = &VectorConfig{}
+	}
+	vectorDB := newDB(db, schema, config, ok)
+
+	// Add the remaining fields in a stable order
+	names := []string{}
+	for name := range fields {
+		if name != VECTOR_COLUMN_NAME {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		vectorDB.addFieldColumn(name)
+		vectorDB.fields[name] = fields[name]
+	}
+
+	return vectorDB
+}
+
+// addFieldColumn adds a BLOB column for the field to the
+// schema if it does not already have one
+func (db *DB) addFieldColumn(name string) {
+	for _, column := range db.schema.Columns {
+		if column.Name == name {
+			return
+		}
+	}
+	db.schema.Columns = append(db.schema.Columns, &Column{
+		Name: name,
+		Type: "BLOB",
+	})
+}
+
+// Fields returns the names of the collection's vector fields
+func (db *DB) Fields() []string {
+	names := []string{}
+	for name := range db.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isVectorColumn reports whether the column holds vectors -
+// a vector field or the sparse vector - and so can not be
+// filtered, ordered, or grouped by.
+func (db *DB) isVectorColumn(name string) bool {
+	if _, ok := db.columnField(name); ok {
+		return true
+	}
+	return db.config.Sparse && name == SPARSE_VECTOR_COLUMN_NAME
+}
+
+// columnField returns the name of the vector field stored in
+// the column, if any
+func (db *DB) columnField(column string) (string, bool) {
+	name := column
+	if column == db.vectorColumn {
+		name = VECTOR_COLUMN_NAME
+	} else if column == VECTOR_COLUMN_NAME {
+		return "", false
+	}
+	_, ok := db.fields[name]
+	return name, ok
+}
+
+// fieldColumn returns the column the named field is stored in
+func (db *DB) fieldColumn(name string) string {
+	if name == "" || name == VECTOR_COLUMN_NAME {
+		return db.vectorColumn
+	}
+	return name
+}
+
+// resolveField returns the name of the field; "", and the
+// column of the default field, are the default
+// VECTOR_COLUMN_NAME field
+func (db *DB) resolveField(name string) string {
+	if name == "" || name == db.vectorColumn {
+		return VECTOR_COLUMN_NAME
+	}
+	return name
+}
+
+// field returns the config of the named field; "" is the
+// collection's default field
+func (db *DB) field(name string) (*VectorConfig, error) {
+	name = db.resolveField(name)
+	config, ok := db.fields[name]
+	if !ok {
+		return nil, fmt.Errorf("vector field %s does not exist", name)
+	}
+	return config, nil
+}
+
+func validateFieldVector(name string, config *VectorConfig, vector govector.Vector) error {
+	if config.MultiVector {
+		if len(vector) == 0 || config.Length <= 0 || len(vector)%config.Length != 0 {
+			return fmt.Errorf(
+				"multi-vector length %d is not a whole number of rows of length %d for field %s",
+				len(vector),
+				config.Length,
+				name,
+			)
+		}
+		return nil
+	}
+	if len(vector) != config.Length {
+		return fmt.Errorf(
+			"vector length %d does not match expected length %d for field %s",
+			len(vector),
+			config.Length,
+			name,
+		)
+	}
+	return nil
+}
+
+// AddField adds a new vector field to the collection and
+// migrates the table to hold it. Existing rows have no value
+// for the field until one is set via SetField.
+func (db *DB) AddField(name string, config *VectorConfig) error {
+	if db.adopted {
+		return fmt.Errorf("table %s was adopted and can not be migrated", db.schema.Name)
+	}
+	if _, ok := db.fields[name]; ok {
+		return fmt.Errorf("vector field %s already exists", name)
+	}
+	for _, column := range db.schema.Columns {
+		if column.Name == name {
+			return fmt.Errorf("column %s already exists", name)
+		}
+	}
+	if _, err := encodingSize(config.Encoding); err != nil {
+		return err
+	}
+
+	db.addFieldColumn(name)
+	db.fields[name] = config
+
+	return db.Migrate()
+}
+
+// SetField sets the value of a single vector field of the
+// stored vector with the given rowid, ie to backfill a newly
+// added field.
+func (db *DB) SetField(rowID int64, name string, vector govector.Vector) error {
+	config, err := db.field(name)
+	if err != nil {
+		return err
+	}
+	name = db.resolveField(name)
+	if err := validateFieldVector(name, config, vector); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE rowid = ?`, db.schema.Name, db.fieldColumn(name))
+	result, err := db.db.Exec(query, encodeVector(vector, config.Encoding), rowID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no vector found with rowid %d", rowID)
+	}
+	return nil
+}
+
+// fieldName returns the name of the vector field the options
+// search; the collection's default field if none is set
+func (db *DB) fieldName(options *FilterOptions) string {
+	if options == nil {
+		return VECTOR_COLUMN_NAME
+	}
+	return db.resolveField(options.Field)
+}
+
+// fieldOptions resolves the vector field searched by the
+// options. It returns a copy of the options with the Field
+// set, and whose SimilarityOptions use the field's metric if
+// it has one, and MaxSim if it is a multi-vector field.
+func (db *DB) fieldOptions(options *FilterOptions) (*FilterOptions, *VectorConfig, error) {
+	config, err := db.field(options.Field)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved := *options
+	resolved.Field = db.fieldName(options)
+	if config.Method != nil || config.MultiVector {
+		similarityOptions := *options.similarityOptions()
+		if config.Method != nil {
+			similarityOptions.Method = *config.Method
+		}
+		if config.MultiVector {
+			similarityOptions.MaxSim = true
+			similarityOptions.MultiVectorLength = config.Length
+		}
+		resolved.SimilarityOptions = &similarityOptions
+	}
+
+	return &resolved, config, nil
+}
+
+// fieldVectors drops any vectors without a value for the
+// field, and returns the remainder alongside a projection of
+// each whose Vector is the field's value, for scoring.
+func fieldVectors(vectors []*Vector, field string) ([]*Vector, []*Vector) {
+	kept := []*Vector{}
+	projected := []*Vector{}
+	for _, vector := range vectors {
+		value := vector.Field(field)
+		if len(value) == 0 {
+			continue
+		}
+		kept = append(kept, vector)
+		projected = append(projected, &Vector{
+			RowID:    vector.RowID,
+			Metadata: vector.Metadata,
+			Vector:   value,
+		})
+	}
+	return kept, projected
+}
@@ -0,0 +1,162 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVectorEncodings(t *testing.T) {
+	vector := govector.Vector{0.5, -1.25, 3}
+
+	for _, encoding := range []int{ENCODING_FLOAT64, ENCODING_FLOAT32} {
+		bytes := encodeVector(vector, encoding)
+		size, err := encodingSize(encoding)
+		require.Nil(t, err)
+		assert.Len(t, bytes, len(vector)*size)
+
+		decoded, err := decodeVector(bytes, encoding)
+		require.Nil(t, err)
+		assert.Equal(t, vector, decoded)
+	}
+
+	_, err := decodeVector([]byte{1, 2, 3}, ENCODING_FLOAT32)
+	assert.NotNil(t, err)
+	_, err = decodeVector([]byte{}, 7)
+	assert.NotNil(t, err)
+}
+
+func TestNamedFields(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	dotProduct := DOT_PRODUCT
+	db := NewDBWithFields(sqlite, &Schema{
+		Name: "documents",
+		Columns: []*Column{
+			{
+				Name: "category",
+				Type: "TEXT",
+			},
+		},
+	}, map[string]*VectorConfig{
+		"title": {Length: 2, Encoding: ENCODING_FLOAT32, Method: &dotProduct},
+		"body":  {Length: 3},
+	})
+	require.Nil(t, db.Migrate())
+	assert.Equal(t, []string{"body", "title"}, db.Fields())
+
+	// There is no default vector column
+	schema, err := FromSQL(sqlite, "documents")
+	require.Nil(t, err)
+	assert.True(t, db.schema.Equal(schema))
+	assert.Len(t, schema.Columns, 3)
+
+	documents := []*Vector{
+		{Fields: map[string]govector.Vector{"title": {1, 0}, "body": {0, 0, 1}}},
+		{Fields: map[string]govector.Vector{"title": {0.5, 0.5}, "body": {1, 0, 0}}},
+		{Fields: map[string]govector.Vector{"title": {2, 0}}},
+	}
+	for _, document := range documents {
+		require.Nil(t, db.Insert(document))
+	}
+
+	// Unknown fields and wrong lengths are rejected
+	assert.NotNil(t, db.Insert(&Vector{Fields: map[string]govector.Vector{"summary": {1}}}))
+	assert.NotNil(t, db.Insert(&Vector{Fields: map[string]govector.Vector{"title": {1, 0, 0}}}))
+
+	// Each field is read back as stored
+	stored, err := db.GetByRowID(1, 3)
+	require.Nil(t, err)
+	assert.Equal(t, govector.Vector{1, 0}, stored[0].Field("title"))
+	assert.Equal(t, govector.Vector{0, 0, 1}, stored[0].Field("body"))
+	assert.Nil(t, stored[1].Field("body"))
+
+	// Title searches use the field's dot product metric,
+	// whatever the options say
+	options := &FilterOptions{SimilarityOptions: DefaultSimilarityOptions, Field: "title"}
+	results, err := db.Search(&Vector{Vector: govector.Vector{1, 0}}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+	assert.Equal(t, int64(3), results.Results[0].Vector.RowID)
+	assert.Equal(t, 2.0, results.Results[0].Score)
+
+	// Body searches skip rows without a body
+	options.Field = "body"
+	results, err = db.Search(&Vector{Vector: govector.Vector{1, 0, 0}}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, int64(2), results.Results[0].Vector.RowID)
+
+	// ...until one is set
+	require.Nil(t, db.SetField(3, "body", govector.Vector{1, 0.1, 0}))
+	assert.NotNil(t, db.SetField(3, "body", govector.Vector{1}))
+	assert.NotNil(t, db.SetField(99, "body", govector.Vector{1, 0, 0}))
+	results, err = db.Search(&Vector{Vector: govector.Vector{1, 0, 0}}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+
+	// Targets must match the field, and the field exist
+	_, err = db.Search(&Vector{Vector: govector.Vector{1, 0}}, nil, options)
+	assert.NotNil(t, err)
+	_, err = db.Search(&Vector{Vector: govector.Vector{1, 0, 0}}, nil, &FilterOptions{})
+	assert.NotNil(t, err)
+
+	// Searching by a stored vector uses its value for the
+	// field searched
+	byID, err := db.QuerySimilarityByID(int64(2), nil, options)
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), byID.Results[0].Vector.RowID)
+
+	recommended, err := db.Recommend(&RecommendQuery{PositiveIDs: []int64{1}}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, recommended.Results, 2)
+
+	// Fields can't be filtered on
+	_, err = db.Query(&Filter{Metadata: []ColumnFilter{{Column: "title", Operation: "=", Value: 1}}})
+	assert.NotNil(t, err)
+}
+
+func TestAddField(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Metadata: map[string]interface{}{"category": "a"}, Vector: govector.Vector{1, 0, 0}},
+		{Metadata: map[string]interface{}{"category": "b"}, Vector: govector.Vector{0, 1, 0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	require.Nil(t, db.AddField("summary", &VectorConfig{Length: 2}))
+	assert.NotNil(t, db.AddField("summary", &VectorConfig{Length: 2}))
+	assert.NotNil(t, db.AddField("category", &VectorConfig{Length: 2}))
+
+	// The existing rows survive the migration with their
+	// rowids, and without a value for the new field
+	stored, err := db.GetByRowID(vectors[0].RowID, vectors[1].RowID)
+	require.Nil(t, err)
+	assert.Equal(t, "a", stored[0].Metadata["category"])
+	assert.Equal(t, govector.Vector{0, 1, 0}, stored[1].Vector)
+	assert.Nil(t, stored[0].Field("summary"))
+
+	require.Nil(t, db.SetField(vectors[1].RowID, "summary", govector.Vector{0, 1}))
+	require.Nil(t, db.Insert(&Vector{
+		Metadata: map[string]interface{}{"category": "c"},
+		Vector:   govector.Vector{0, 0, 1},
+		Fields:   map[string]govector.Vector{"summary": {1, 0}},
+	}))
+
+	options := &FilterOptions{SimilarityOptions: DefaultSimilarityOptions, Field: "summary"}
+	results, err := db.Search(&Vector{Vector: govector.Vector{1, 0}}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, "c", results.Results[0].Vector.Metadata["category"])
+	assert.Equal(t, "b", results.Results[1].Vector.Metadata["category"])
+}
@@ -0,0 +1,217 @@
+package gsvt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Operations supported within a ColumnFilter
+const OP_EQUAL = "="
+const OP_NOT_EQUAL = "!="
+const OP_GREATER = ">"
+const OP_GREATER_EQUAL = ">="
+const OP_LESS = "<"
+const OP_LESS_EQUAL = "<="
+const OP_LIKE = "LIKE"
+const OP_IN = "IN"
+const OP_NOT_IN = "NOT IN"
+
+// validOperations is the set of operations we allow in a
+// ColumnFilter. Aliases that SQLite understands are included
+// so hand built filters continue to work.
+var validOperations = map[string]bool{
+	OP_EQUAL:         true,
+	"==":             true,
+	OP_NOT_EQUAL:     true,
+	"<>":             true,
+	OP_GREATER:       true,
+	OP_GREATER_EQUAL: true,
+	OP_LESS:          true,
+	OP_LESS_EQUAL:    true,
+	OP_LIKE:          true,
+	OP_IN:            true,
+	OP_NOT_IN:        true,
+}
+
+// FilterBuilder fluently builds a Filter, ie:
+//
+//	Where("year").Gte(2020).And(Where("tag").In("a", "b"))
+//
+// Nothing is checked until Build is called with the DB the
+// filter is meant for.
+type FilterBuilder struct {
+	conditions []ColumnFilter
+}
+
+// ConditionBuilder is a single column awaiting its operation
+// and value. Each operation returns the owning FilterBuilder
+// so that further conditions can be chained.
+type ConditionBuilder struct {
+	builder *FilterBuilder
+	column  string
+}
+
+// Where starts a new FilterBuilder with a condition on the
+// given column.
+func Where(column string) *ConditionBuilder {
+	return (&FilterBuilder{}).Where(column)
+}
+
+// Where adds another condition on the given column to the
+// existing builder.
+func (f *FilterBuilder) Where(column string) *ConditionBuilder {
+	return &ConditionBuilder{
+		builder: f,
+		column:  column,
+	}
+}
+
+// And merges the conditions of the other builder into this
+// one. All conditions must match for a vector to be returned.
+func (f *FilterBuilder) And(other *FilterBuilder) *FilterBuilder {
+	if other != nil {
+		f.conditions = append(f.conditions, other.conditions...)
+	}
+	return f
+}
+
+// Build checks each condition against the DB's schema -
+// ensuring that the column exists, does not hold vectors,
+// and that the value is of a type the column can hold - and
+// returns the resulting Filter.
+func (f *FilterBuilder) Build(db *DB) (*Filter, error) {
+	columns := map[string]*Column{}
+	for _, column := range db.schema.Columns {
+		columns[column.Name] = column
+	}
+
+	filter := &Filter{
+		Metadata: []ColumnFilter{},
+	}
+	for _, condition := range f.conditions {
+		column, ok := columns[condition.Column]
+		if !ok {
+			return nil, fmt.Errorf("column %s does not exist", condition.Column)
+		} else if db.isVectorColumn(column.Name) {
+			return nil, fmt.Errorf("you can not specify %s in your query filter", column.Name)
+		}
+
+		if condition.Operation == OP_IN || condition.Operation == OP_NOT_IN {
+			for _, value := range condition.Value.([]interface{}) {
+				if err := checkColumnValue(column, value); err != nil {
+					return nil, err
+				}
+			}
+		} else if err := checkColumnValue(column, condition.Value); err != nil {
+			return nil, err
+		}
+
+		filter.Metadata = append(filter.Metadata, condition)
+	}
+
+	return filter, nil
+}
+
+func (c *ConditionBuilder) add(operation string, value interface{}) *FilterBuilder {
+	c.builder.conditions = append(c.builder.conditions, ColumnFilter{
+		Column:    c.column,
+		Operation: operation,
+		Value:     value,
+	})
+	return c.builder
+}
+
+// Eq matches rows where the column equals the value
+func (c *ConditionBuilder) Eq(value interface{}) *FilterBuilder {
+	return c.add(OP_EQUAL, value)
+}
+
+// Ne matches rows where the column does not equal the value
+func (c *ConditionBuilder) Ne(value interface{}) *FilterBuilder {
+	return c.add(OP_NOT_EQUAL, value)
+}
+
+// Gt matches rows where the column is greater than the value
+func (c *ConditionBuilder) Gt(value interface{}) *FilterBuilder {
+	return c.add(OP_GREATER, value)
+}
+
+// Gte matches rows where the column is greater than or equal
+// to the value
+func (c *ConditionBuilder) Gte(value interface{}) *FilterBuilder {
+	return c.add(OP_GREATER_EQUAL, value)
+}
+
+// Lt matches rows where the column is less than the value
+func (c *ConditionBuilder) Lt(value interface{}) *FilterBuilder {
+	return c.add(OP_LESS, value)
+}
+
+// Lte matches rows where the column is less than or equal
+// to the value
+func (c *ConditionBuilder) Lte(value interface{}) *FilterBuilder {
+	return c.add(OP_LESS_EQUAL, value)
+}
+
+// Like matches rows where the column matches the SQL LIKE
+// pattern
+func (c *ConditionBuilder) Like(pattern string) *FilterBuilder {
+	return c.add(OP_LIKE, pattern)
+}
+
+// In matches rows where the column is any of the values
+func (c *ConditionBuilder) In(values ...interface{}) *FilterBuilder {
+	return c.add(OP_IN, values)
+}
+
+// NotIn matches rows where the column is none of the values
+func (c *ConditionBuilder) NotIn(values ...interface{}) *FilterBuilder {
+	return c.add(OP_NOT_IN, values)
+}
+
+// checkColumnValue ensures that the value is something the
+// column can reasonably be compared against. We go by SQLite's
+// type affinity rules on the declared column type; types we
+// do not recognize accept anything.
+func checkColumnValue(column *Column, value interface{}) error {
+	if value == nil {
+		return fmt.Errorf("column %s can not be compared against nil", column.Name)
+	}
+
+	kind := reflect.TypeOf(value).Kind()
+	isInteger := kind >= reflect.Int && kind <= reflect.Uint64
+	isFloat := kind == reflect.Float32 || kind == reflect.Float64
+
+	columnType := strings.ToUpper(column.Type)
+	accepted := true
+	switch {
+	case strings.Contains(columnType, "INT"):
+		accepted = isInteger
+	case strings.Contains(columnType, "CHAR"),
+		strings.Contains(columnType, "CLOB"),
+		strings.Contains(columnType, "TEXT"):
+		accepted = kind == reflect.String
+	case strings.Contains(columnType, "BLOB"):
+		_, accepted = value.([]byte)
+	case strings.Contains(columnType, "REAL"),
+		strings.Contains(columnType, "FLOA"),
+		strings.Contains(columnType, "DOUB"):
+		accepted = isInteger || isFloat
+	case strings.Contains(columnType, "TIME"),
+		strings.Contains(columnType, "DATE"):
+		_, accepted = value.(time.Time)
+	}
+
+	if !accepted {
+		return fmt.Errorf(
+			"value of type %T is not valid for column %s of type %s",
+			value,
+			column.Name,
+			column.Type,
+		)
+	}
+
+	return nil
+}
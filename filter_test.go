@@ -0,0 +1,153 @@
+package gsvt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	schema := &Schema{
+		Name: "test",
+		Columns: []*Column{
+			{Name: "year", Type: "INTEGER"},
+			{Name: "tag", Type: "TEXT"},
+			{Name: "score", Type: "REAL"},
+			{Name: "created_at", Type: "TIMESTAMP"},
+			{Name: VECTOR_COLUMN_NAME, Type: "BLOB"},
+		},
+	}
+	db := NewDB(nil, schema, &VectorConfig{Length: 3})
+
+	// A valid filter should build into the expected
+	// set of column filters
+	filter, err := Where("year").Gte(2020).
+		And(Where("tag").In("a", "b")).
+		And(Where("score").Lt(5)).
+		Where("created_at").Lte(time.Now()).
+		Build(db)
+	require.Nil(t, err)
+	require.NotNil(t, filter)
+	require.Len(t, filter.Metadata, 4)
+	assert.Equal(t, "year", filter.Metadata[0].Column)
+	assert.Equal(t, OP_GREATER_EQUAL, filter.Metadata[0].Operation)
+	assert.Equal(t, 2020, filter.Metadata[0].Value)
+	assert.Equal(t, OP_IN, filter.Metadata[1].Operation)
+	assert.Equal(t, []interface{}{"a", "b"}, filter.Metadata[1].Value)
+	assert.Equal(t, "created_at", filter.Metadata[3].Column)
+
+	// Non existent columns are rejected
+	_, err = Where("fake").Eq("value").Build(db)
+	assert.NotNil(t, err)
+
+	// The vector column can not be filtered on
+	_, err = Where(VECTOR_COLUMN_NAME).Eq([]byte{}).Build(db)
+	assert.NotNil(t, err)
+
+	// Mismatched types are rejected
+	_, err = Where("year").Eq("2020").Build(db)
+	assert.NotNil(t, err)
+	_, err = Where("tag").In("a", 2).Build(db)
+	assert.NotNil(t, err)
+	_, err = Where("created_at").Gt("yesterday").Build(db)
+	assert.NotNil(t, err)
+	_, err = Where("tag").Eq(nil).Build(db)
+	assert.NotNil(t, err)
+}
+
+func TestFilterBuilderVectorColumns(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Named fields and the sparse vector can't be filtered
+	// on, any more than the default field can
+	db := NewDBWithFields(sqlite, &Schema{
+		Columns: []*Column{{Name: "tag", Type: "TEXT"}},
+	}, map[string]*VectorConfig{
+		VECTOR_COLUMN_NAME: {Length: 3, Sparse: true},
+		"summary":          {Length: 3},
+	})
+	require.Nil(t, db.Migrate())
+	_, err = Where("summary").Eq([]byte{1}).Build(db)
+	assert.NotNil(t, err)
+	_, err = Where(SPARSE_VECTOR_COLUMN_NAME).Eq([]byte{1}).Build(db)
+	assert.NotNil(t, err)
+	_, err = Where("tag").Eq("a").Build(db)
+	assert.Nil(t, err)
+
+	// On an adopted table the adopted column holds the
+	// vectors, and a column named "vector" is just metadata
+	_, err = sqlite.Exec(`CREATE TABLE notes (
+		id INTEGER PRIMARY KEY,
+		vector TEXT,
+		embedding BLOB
+	)`)
+	require.Nil(t, err)
+	_, err = sqlite.Exec(`INSERT INTO notes (id, vector) VALUES (1, 'a'), (2, 'b')`)
+	require.Nil(t, err)
+	adopted, err := Adopt(sqlite, "notes", "embedding", &VectorConfig{Length: 3})
+	require.Nil(t, err)
+	assert.Equal(t, "notes", adopted.Schema().Name)
+
+	_, err = Where("embedding").Eq([]byte{1}).Build(adopted)
+	assert.NotNil(t, err)
+	filter, err := Where("vector").Eq("b").Build(adopted)
+	require.Nil(t, err)
+	vectors, err := adopted.Query(filter)
+	require.Nil(t, err)
+	require.Len(t, vectors, 1)
+	assert.Equal(t, int64(2), vectors[0].Metadata["id"])
+}
+
+func TestQueryWithFilterBuilder(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Setup our db and vectors
+	db, vectors, _, err := setupVectorsAndDB(sqlite)
+	require.Nil(t, err)
+
+	// Build an IN filter and ensure that it matches
+	// the same vectors as the equivalent set of
+	// != filters
+	filter, err := Where("user").In("user1", "user2").Build(db)
+	require.Nil(t, err)
+	foundVectors, err := db.Query(filter)
+	require.Nil(t, err)
+
+	comparableVectors := roughQuery(db, vectors, &Filter{
+		Metadata: []ColumnFilter{
+			{
+				Column:    "user",
+				Operation: "!=",
+				Value:     "user3",
+			},
+		},
+	})
+	assert.Len(t, foundVectors, len(comparableVectors))
+
+	// NOT IN should return the remainder
+	filter, err = Where("user").NotIn("user1", "user2").Build(db)
+	require.Nil(t, err)
+	excludedVectors, err := db.Query(filter)
+	require.Nil(t, err)
+	assert.Len(t, excludedVectors, len(vectors)-len(comparableVectors))
+
+	// Unknown operations are caught before hitting SQL
+	_, err = db.Query(&Filter{
+		Metadata: []ColumnFilter{
+			{
+				Column:    "user",
+				Operation: "=>",
+				Value:     "user1",
+			},
+		},
+	})
+	assert.NotNil(t, err)
+}
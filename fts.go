@@ -0,0 +1,272 @@
+package gsvt
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// HybridOptions control how HybridSearch fuses the vector
+// and full text rankings.
+type HybridOptions struct {
+	// Fusion is one of the FUSION_* methods, as described
+	// in FusionOptions. FUSION_WEIGHTED is FUSION_COMBSUM.
+	Fusion int
+
+	// K is the RRF rank constant. If 0, 60 is used.
+	K float64
+
+	// VectorWeight and TextWeight weight each ranking's
+	// contribution to the fused score. If both are 0, each
+	// is 1.
+	VectorWeight float64
+	TextWeight   float64
+}
+
+var DefaultHybridOptions *HybridOptions = &HybridOptions{
+	Fusion:       FUSION_RRF,
+	K:            60,
+	VectorWeight: 1,
+	TextWeight:   1,
+}
+
+// fullTextTableName is the name of the FTS5 table for the
+// schema
+func (db *DB) fullTextTableName() string {
+	return fmt.Sprintf("%s_fts", db.schema.Name)
+}
+
+// migrateFullText (re)creates the FTS5 table for the
+// schema's FullTextColumn, and the triggers that keep it in
+// sync with the table. It is rebuilt each time as the column
+// may have changed, and altering the table recreates it,
+// which drops its triggers. If there is no FullTextColumn,
+// any prior FTS5 table and triggers are dropped.
+func (db *DB) migrateFullText() error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.migrateFullTextTx(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateFullTextTx is migrateFullText within the given
+// transaction
+func (db *DB) migrateFullTextTx(tx *sql.Tx) error {
+	table := db.schema.Name
+	ftsTable := db.fullTextTableName()
+	column := db.schema.FullTextColumn
+
+	queries := []string{
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_ai`, ftsTable),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_ad`, ftsTable),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_au`, ftsTable),
+		fmt.Sprintf(`DROP TABLE IF EXISTS %s`, ftsTable),
+	}
+
+	if column != "" {
+		if err := db.validateFullTextColumn(); err != nil {
+			return err
+		}
+
+		queries = append(queries,
+			fmt.Sprintf(
+				`CREATE VIRTUAL TABLE %s USING fts5(%s, content='%s', content_rowid='rowid')`,
+				ftsTable, column, table,
+			),
+			fmt.Sprintf(
+				`CREATE TRIGGER %s_ai AFTER INSERT ON %s BEGIN `+
+					`INSERT INTO %s(rowid, %s) VALUES (new.rowid, new.%s); END`,
+				ftsTable, table, ftsTable, column, column,
+			),
+			fmt.Sprintf(
+				`CREATE TRIGGER %s_ad AFTER DELETE ON %s BEGIN `+
+					`INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, old.%s); END`,
+				ftsTable, table, ftsTable, ftsTable, column, column,
+			),
+			fmt.Sprintf(
+				`CREATE TRIGGER %s_au AFTER UPDATE ON %s BEGIN `+
+					`INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, old.%s); `+
+					`INSERT INTO %s(rowid, %s) VALUES (new.rowid, new.%s); END`,
+				ftsTable, table, ftsTable, ftsTable, column, column, ftsTable, column, column,
+			),
+			// Index any rows that already exist
+			fmt.Sprintf(`INSERT INTO %s(%s) VALUES ('rebuild')`, ftsTable, ftsTable),
+		)
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) validateFullTextColumn() error {
+	name := db.schema.FullTextColumn
+	if name == "" {
+		return fmt.Errorf("schema %s has no full text column", db.schema.Name)
+	}
+	for _, column := range db.schema.Columns {
+		if column.Name == name {
+			if !strings.Contains(strings.ToUpper(column.Type), "TEXT") {
+				return fmt.Errorf("full text column %s must be of type TEXT", name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("column %s does not exist", name)
+}
+
+// fullTextMatch runs the text against the FTS5 table and
+// returns the BM25 score of each matching rowid. Note that
+// SQLite's bm25 is negative, where lower is better. The
+// text is split into terms which are each quoted so that
+// user input can't be misread as FTS5 query syntax; any
+// term may match.
+func (db *DB) fullTextMatch(text string) (map[int64]float64, error) {
+	matches := map[int64]float64{}
+
+	quoted := []string{}
+	for term := range terms(text) {
+		quoted = append(quoted, fmt.Sprintf(`"%s"`, term))
+	}
+	if len(quoted) == 0 {
+		return matches, nil
+	}
+	sort.Strings(quoted)
+
+	ftsTable := db.fullTextTableName()
+	query := fmt.Sprintf(
+		`SELECT rowid, bm25(%s) FROM %s WHERE %s MATCH ?`,
+		ftsTable, ftsTable, ftsTable,
+	)
+	rows, err := db.db.Query(query, strings.Join(quoted, " OR "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rowID int64
+		var score float64
+		if err := rows.Scan(&rowID, &score); err != nil {
+			return nil, err
+		}
+		matches[rowID] = score
+	}
+
+	return matches, rows.Err()
+}
+
+// HybridSearch ranks the vectors matching the filter by both
+// their similarity to the target and the BM25 score of the
+// text against the schema's FullTextColumn, fusing the two
+// rankings as per the hybrid options. The fused score is
+// then cut off, paged, etc as in Search; each result's
+// Similarity is still its vector similarity alone. As the
+// fused score is not a similarity, MinScore and MaxDistance
+// can not be used. If hybrid is nil, DefaultHybridOptions
+// are used.
+func (db *DB) HybridSearch(target *Vector, text string, filter *Filter, options *FilterOptions, hybrid *HybridOptions) (*SearchResults, error) {
+	if options == nil {
+		options = &DefaultFilterOptions
+	}
+	if hybrid == nil {
+		hybrid = DefaultHybridOptions
+	}
+	if hybrid.K == 0 || (hybrid.VectorWeight == 0 && hybrid.TextWeight == 0) {
+		defaulted := *hybrid
+		if defaulted.K == 0 {
+			defaulted.K = DefaultHybridOptions.K
+		}
+		if defaulted.VectorWeight == 0 && defaulted.TextWeight == 0 {
+			defaulted.VectorWeight = DefaultHybridOptions.VectorWeight
+			defaulted.TextWeight = DefaultHybridOptions.TextWeight
+		}
+		hybrid = &defaulted
+	}
+	if err := db.validateFullTextColumn(); err != nil {
+		return nil, err
+	}
+	options, config, err := db.fieldOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.validateFilterOptions(options); err != nil {
+		return nil, err
+	}
+	if err := validateFieldVector(options.Field, config, target.Vector); err != nil {
+		return nil, err
+	}
+	if options.Matryoshka != nil {
+		return nil, fmt.Errorf("matryoshka search is not supported by HybridSearch")
+	}
+	if options.MinScore != nil || options.MaxDistance != nil {
+		return nil, fmt.Errorf("score thresholds are not supported by HybridSearch")
+	}
+
+	vectors, err := db.candidates(filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	vectors, projected := fieldVectors(vectors, options.Field)
+	similarities, err := target.SimilarityToVectorSet(projected, options.SimilarityOptions)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := db.fullTextMatch(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Full text scores aligned to our candidates; bm25 is
+	// flipped so that higher is better like our similarity.
+	// Non matches are NaN.
+	textScores := make([]float64, len(vectors))
+	for index, vector := range vectors {
+		if score, ok := matches[vector.RowID]; ok {
+			textScores[index] = -score
+		} else {
+			textScores[index] = math.NaN()
+		}
+	}
+
+	fusion := &FusionOptions{
+		Method:  hybrid.Fusion,
+		K:       hybrid.K,
+		Weights: []float64{hybrid.VectorWeight, hybrid.TextWeight},
+	}
+	if err := validateFusion(fusion, 2); err != nil {
+		return nil, err
+	}
+
+	rowIDs := make([]int64, len(vectors))
+	for index, vector := range vectors {
+		rowIDs[index] = vector.RowID
+	}
+	// Distances are likewise flipped before fusing
+	vectorScores := similarities
+	if isDistanceMetric(options.similarityOptions().Method) {
+		vectorScores = make([]float64, len(similarities))
+		for index, similarity := range similarities {
+			vectorScores[index] = -similarity
+		}
+	}
+	fused := fuseScores(
+		[][]float64{vectorScores, textScores},
+		[][]int{ranks(rowIDs, vectorScores), ranks(rowIDs, textScores)},
+		fusion,
+	)
+
+	return rankResults(target, vectors, similarities, fused, options)
+}
@@ -0,0 +1,182 @@
+package gsvt
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupFullTextDB is setupSmallDB with a full text index on
+// "category". It skips the test if SQLite was built without
+// FTS5 (see the sqlite_fts5 build tag).
+func setupFullTextDB(t *testing.T, sqlite *sql.DB, vectors []*Vector) *DB {
+	db := NewDB(sqlite, &Schema{
+		Columns: []*Column{
+			{
+				Name: "category",
+				Type: "TEXT",
+			},
+		},
+		FullTextColumn: "category",
+	}, &VectorConfig{
+		Length: 3,
+	})
+
+	err := db.Migrate()
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		t.Skip("sqlite3 was built without fts5; use -tags sqlite_fts5")
+	}
+	require.Nil(t, err)
+
+	for _, vector := range vectors {
+		require.Nil(t, db.Insert(vector))
+	}
+
+	return db
+}
+
+func TestFullTextColumnValidation(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := NewDB(sqlite, &Schema{
+		Columns: []*Column{
+			{
+				Name: "count",
+				Type: "INTEGER",
+			},
+		},
+		FullTextColumn: "count",
+	}, &VectorConfig{
+		Length: 3,
+	})
+	assert.NotNil(t, db.Migrate())
+
+	db.schema.FullTextColumn = "missing"
+	assert.NotNil(t, db.Migrate())
+
+	// Without a full text column we can not hybrid search
+	db.schema.FullTextColumn = ""
+	require.Nil(t, db.Migrate())
+	_, err = db.HybridSearch(&Vector{Vector: govector.Vector{1, 0, 0}}, "text", nil, nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestHybridSearch(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := setupFullTextDB(t, sqlite, []*Vector{
+		{
+			Metadata: map[string]interface{}{"category": "red apple"},
+			Vector:   govector.Vector{1.0, 0.0, 0.0},
+		},
+		{
+			Metadata: map[string]interface{}{"category": "green pear"},
+			Vector:   govector.Vector{0.9, 0.1, 0.0},
+		},
+		{
+			Metadata: map[string]interface{}{"category": "yellow banana"},
+			Vector:   govector.Vector{0.0, 1.0, 0.0},
+		},
+		{
+			Metadata: map[string]interface{}{"category": "banana bread"},
+			Vector:   govector.Vector{0.0, 0.0, 1.0},
+		},
+	})
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	options := &FilterOptions{SimilarityOptions: DefaultSimilarityOptions}
+
+	// With no text, only the vector ranking counts
+	results, err := db.HybridSearch(target, "", nil, options, nil)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 4)
+	assert.Equal(t, int64(1), results.Results[0].Vector.RowID)
+	assert.Equal(t, int64(2), results.Results[1].Vector.RowID)
+
+	// Both banana rows now gain a text rank; the pear has
+	// only its vector rank, and the apple leads on vector
+	// rank alone.
+	results, err = db.HybridSearch(target, "Banana!", nil, options, &HybridOptions{
+		Fusion:       FUSION_RRF,
+		K:            1,
+		VectorWeight: 1,
+		TextWeight:   2,
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 4)
+	rowIDs := []int64{}
+	for _, result := range results.Results {
+		rowIDs = append(rowIDs, result.Vector.RowID)
+	}
+	assert.Equal(t, []int64{3, 4, 1, 2}, rowIDs)
+	// Similarity is still the vector similarity
+	assert.InDelta(t, 0.0, results.Results[0].Similarity, 1e-9)
+
+	// Weighted fusion entirely on text
+	results, err = db.HybridSearch(target, "bread", nil, options, &HybridOptions{
+		Fusion:     FUSION_WEIGHTED,
+		TextWeight: 1,
+	})
+	require.Nil(t, err)
+	assert.Equal(t, int64(4), results.Results[0].Vector.RowID)
+	assert.Equal(t, 1.0, results.Results[0].Score)
+	assert.Equal(t, 0.0, results.Results[1].Score)
+
+	// The filter still applies to text matches
+	filter, err := Where("category").Eq("banana bread").Build(db)
+	require.Nil(t, err)
+	results, err = db.HybridSearch(target, "banana", filter, options, nil)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 1)
+	assert.Equal(t, int64(4), results.Results[0].Vector.RowID)
+
+	// The index follows updates and deletes
+	_, err = sqlite.Exec(`UPDATE VectorCollection SET category = 'pear bread' WHERE rowid = 2`)
+	require.Nil(t, err)
+	_, err = sqlite.Exec(`DELETE FROM VectorCollection WHERE rowid = 4`)
+	require.Nil(t, err)
+	matches, err := db.fullTextMatch("bread")
+	require.Nil(t, err)
+	assert.Len(t, matches, 1)
+	assert.Contains(t, matches, int64(2))
+
+	_, err = db.HybridSearch(target, "banana", nil, nil, &HybridOptions{Fusion: 7})
+	assert.NotNil(t, err)
+
+	// Unset weights and K fall back to the defaults rather
+	// than zeroing every score
+	defaulted, err := db.HybridSearch(target, "banana", nil, options, DefaultHybridOptions)
+	require.Nil(t, err)
+	results, err = db.HybridSearch(target, "banana", nil, options, &HybridOptions{Fusion: FUSION_RRF})
+	require.Nil(t, err)
+	require.Len(t, results.Results, len(defaulted.Results))
+	for index, result := range results.Results {
+		assert.Equal(t, defaulted.Results[index].Vector.RowID, result.Vector.RowID)
+		assert.Equal(t, defaulted.Results[index].Score, result.Score)
+		assert.Greater(t, result.Score, 0.0)
+	}
+
+	// Thresholds are for the vector metric, not the fused
+	// score, so are rejected
+	minScore := 0.5
+	_, err = db.HybridSearch(target, "banana", nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		MinScore:          &minScore,
+	}, nil)
+	assert.NotNil(t, err)
+	maxDistance := 0.5
+	_, err = db.HybridSearch(target, "banana", nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		MaxDistance:       &maxDistance,
+	}, nil)
+	assert.NotNil(t, err)
+}
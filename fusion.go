@@ -0,0 +1,272 @@
+package gsvt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Methods of fusing several rankings into one
+const FUSION_RRF = 0
+const FUSION_COMBSUM = 1
+const FUSION_COMBMNZ = 2
+
+// FUSION_WEIGHTED is a weighted sum of each ranking's
+// normalized scores, ie FUSION_COMBSUM
+const FUSION_WEIGHTED = FUSION_COMBSUM
+
+// FusionOptions control how Fuse merges ranked lists.
+type FusionOptions struct {
+	// Method is one of:
+	//
+	// FUSION_RRF - reciprocal rank fusion; each list a
+	// result appears in contributes weight / (K + rank).
+	//
+	// FUSION_COMBSUM - each list's scores are min-max
+	// normalized to [0, 1], best highest, then summed by
+	// weight.
+	//
+	// FUSION_COMBMNZ - FUSION_COMBSUM multiplied by the
+	// number of lists the result appears in.
+	Method int
+
+	// K is the RRF rank constant. If 0, 60 is used.
+	K float64
+
+	// Weights weights each list's contribution, in the
+	// order the lists are given. If nil, every list has a
+	// weight of 1.
+	Weights []float64
+
+	// Limit is the maximum number of fused results to
+	// return; 0 returns all of them.
+	Limit int
+}
+
+var DefaultFusionOptions *FusionOptions = &FusionOptions{
+	Method: FUSION_RRF,
+	K:      60,
+}
+
+func validateFusion(options *FusionOptions, lists int) error {
+	switch options.Method {
+	case FUSION_RRF, FUSION_COMBSUM, FUSION_COMBMNZ:
+	default:
+		return fmt.Errorf("unknown fusion method %d", options.Method)
+	}
+	if options.K < 0 {
+		return fmt.Errorf("fusion K must not be negative")
+	}
+	if options.Weights != nil && len(options.Weights) != lists {
+		return fmt.Errorf(
+			"fusion has %d weights for %d ranked lists",
+			len(options.Weights),
+			lists,
+		)
+	}
+	if options.Limit < 0 {
+		return fmt.Errorf("fusion limit must not be negative")
+	}
+	return nil
+}
+
+// Fuse merges several ranked result lists - ie from searches
+// with different targets, metrics, or filters - into a single
+// ranking. Results are identified by their Collection and
+// their vector's rowid, so a row appearing in several lists is
+// fused into one result;
+// it keeps the Vector, Similarity and Relevance from the first
+// list it appears in, and its Score becomes the fused score.
+// Each list is expected to be ordered best first, as returned
+// by Search; RRF ranks each result by its position in its
+// list, whatever its Score. COMBSUM and COMBMNZ flip the
+// Scores of Distance lists so higher is better before
+// normalizing them. If options is nil,
+// DefaultFusionOptions are used.
+func Fuse(options *FusionOptions, lists ...*SearchResults) (*SearchResults, error) {
+	if options == nil {
+		options = DefaultFusionOptions
+	}
+	if err := validateFusion(options, len(lists)); err != nil {
+		return nil, err
+	}
+
+	// Gather each distinct row; rows from different
+	// collections may share a rowid
+	type rowKey struct {
+		collection string
+		rowID      int64
+	}
+	positions := map[rowKey]int{}
+	results := []*SearchResult{}
+	for _, searchResults := range lists {
+		if searchResults == nil {
+			continue
+		}
+		for _, result := range searchResults.Results {
+			key := rowKey{result.Collection, result.Vector.RowID}
+			if _, ok := positions[key]; ok {
+				continue
+			}
+			positions[key] = len(results)
+			results = append(results, &SearchResult{
+				Vector:     result.Vector,
+				Similarity: result.Similarity,
+				Relevance:  result.Relevance,
+				Collection: result.Collection,
+			})
+		}
+	}
+
+	// ...and line up each list's scores and ranks against
+	// them; rows missing from a list are NaN, and unranked
+	scores := make([][]float64, len(lists))
+	listRanks := make([][]int, len(lists))
+	for list, searchResults := range lists {
+		scores[list] = make([]float64, len(results))
+		listRanks[list] = make([]int, len(results))
+		for index := range scores[list] {
+			scores[list][index] = math.NaN()
+		}
+		if searchResults == nil {
+			continue
+		}
+		for position, result := range searchResults.Results {
+			index := positions[rowKey{result.Collection, result.Vector.RowID}]
+			// Only the first, best, occurrence counts
+			if listRanks[list][index] == 0 {
+				scores[list][index] = result.Score
+				if searchResults.Distance {
+					scores[list][index] = -result.Score
+				}
+				listRanks[list][index] = position + 1
+			}
+		}
+	}
+
+	fused := fuseScores(scores, listRanks, options)
+	for index, result := range results {
+		result.Score = fused[index]
+	}
+
+	sortResults(results, false)
+	if options.Limit > 0 && options.Limit < len(results) {
+		results = results[0:options.Limit]
+	}
+
+	fusedResults := &SearchResults{
+		Results: results,
+		Facets:  map[string]*Facet{},
+		Groups:  []*SearchGroup{},
+	}
+	if len(results) > 0 {
+		last := results[len(results)-1]
+		fusedResults.Cursor = &SearchCursor{Score: last.Score, RowID: last.Vector.RowID}
+	}
+
+	return fusedResults, nil
+}
+
+// fuseScores fuses several rankings of the same rows. Each
+// ranking holds a score per row, where NaN marks a row as
+// absent from that ranking, and a 1 based rank per row, where
+// 0 marks it as absent; see ranks. The options are assumed
+// to be valid.
+func fuseScores(rankings [][]float64, rankingRanks [][]int, options *FusionOptions) []float64 {
+	weight := func(ranking int) float64 {
+		if options.Weights == nil {
+			return 1
+		}
+		return options.Weights[ranking]
+	}
+
+	rows := 0
+	if len(rankings) > 0 {
+		rows = len(rankings[0])
+	}
+	fused := make([]float64, rows)
+	appearances := make([]int, rows)
+
+	switch options.Method {
+	case FUSION_RRF:
+		k := options.K
+		if k == 0 {
+			k = DefaultFusionOptions.K
+		}
+		for ranking := range rankings {
+			for index, rank := range rankingRanks[ranking] {
+				if rank > 0 {
+					fused[index] += weight(ranking) / (k + float64(rank))
+				}
+			}
+		}
+	case FUSION_COMBSUM, FUSION_COMBMNZ:
+		for ranking, scores := range rankings {
+			for index, score := range minMaxNormalize(scores) {
+				if !math.IsNaN(score) {
+					fused[index] += weight(ranking) * score
+					appearances[index]++
+				}
+			}
+		}
+		if options.Method == FUSION_COMBMNZ {
+			for index := range fused {
+				fused[index] *= float64(appearances[index])
+			}
+		}
+	}
+
+	return fused
+}
+
+// ranks returns the 1 based rank of each row by its score,
+// highest first with ties broken by rowid. Rows with a NaN
+// score are unranked, and given a rank of 0.
+func ranks(rowIDs []int64, scores []float64) []int {
+	order := []int{}
+	for index, score := range scores {
+		if !math.IsNaN(score) {
+			order = append(order, index)
+		}
+	}
+	sort.Slice(order, func(a int, b int) bool {
+		if scores[order[a]] != scores[order[b]] {
+			return scores[order[a]] > scores[order[b]]
+		}
+		return rowIDs[order[a]] < rowIDs[order[b]]
+	})
+
+	ranked := make([]int, len(scores))
+	for rank, index := range order {
+		ranked[index] = rank + 1
+	}
+	return ranked
+}
+
+// minMaxNormalize scales the scores to [0, 1]. NaN scores
+// are ignored and left as NaN. If all scores are the same,
+// they are all normalized to 1.
+func minMaxNormalize(scores []float64) []float64 {
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	for _, score := range scores {
+		if math.IsNaN(score) {
+			continue
+		}
+		min = math.Min(min, score)
+		max = math.Max(max, score)
+	}
+
+	normalized := make([]float64, len(scores))
+	for index, score := range scores {
+		switch {
+		case math.IsNaN(score):
+			normalized[index] = score
+		case max == min:
+			normalized[index] = 1
+		default:
+			normalized[index] = (score - min) / (max - min)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,160 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rankedList builds search results for the given rowids and
+// scores, in the order given
+func rankedList(rowIDs []int64, scores []float64) *SearchResults {
+	results := &SearchResults{}
+	for index, rowID := range rowIDs {
+		results.Results = append(results.Results, &SearchResult{
+			Vector:     &Vector{RowID: rowID},
+			Score:      scores[index],
+			Similarity: scores[index],
+		})
+	}
+	return results
+}
+
+func fusedRowIDs(results *SearchResults) []int64 {
+	rowIDs := []int64{}
+	for _, result := range results.Results {
+		rowIDs = append(rowIDs, result.Vector.RowID)
+	}
+	return rowIDs
+}
+
+func TestFuse(t *testing.T) {
+	first := rankedList([]int64{1, 2, 3}, []float64{0.9, 0.5, 0.1})
+	second := rankedList([]int64{3, 4}, []float64{0.8, 0.4})
+
+	results, err := Fuse(&FusionOptions{Method: FUSION_RRF, K: 1}, first, second)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{3, 1, 2, 4}, fusedRowIDs(results))
+	assert.InDelta(t, 0.75, results.Results[0].Score, 1e-9)
+	// Row 3 keeps its similarity from the first list
+	assert.Equal(t, 0.1, results.Results[0].Similarity)
+	assert.Equal(t, &SearchCursor{Score: results.Results[3].Score, RowID: 4}, results.Cursor)
+
+	results, err = Fuse(&FusionOptions{Method: FUSION_COMBSUM}, first, second)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{1, 3, 2, 4}, fusedRowIDs(results))
+	assert.Equal(t, []float64{1, 1, 0.5, 0}, []float64{
+		results.Results[0].Score,
+		results.Results[1].Score,
+		results.Results[2].Score,
+		results.Results[3].Score,
+	})
+
+	results, err = Fuse(&FusionOptions{Method: FUSION_COMBMNZ}, first, second)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{3, 1, 2, 4}, fusedRowIDs(results))
+	assert.Equal(t, 2.0, results.Results[0].Score)
+
+	results, err = Fuse(&FusionOptions{
+		Method:  FUSION_COMBSUM,
+		Weights: []float64{2, 1},
+		Limit:   2,
+	}, first, second)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{1, 2}, fusedRowIDs(results))
+
+	// Nil lists and nil options are fine
+	results, err = Fuse(nil, first, nil)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, fusedRowIDs(results))
+
+	// RRF ranks by position, as reranking leaves lists out
+	// of Score order
+	reranked := rankedList([]int64{2, 1}, []float64{0.5, 0.9})
+	results, err = Fuse(&FusionOptions{Method: FUSION_RRF, K: 1}, reranked)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{2, 1}, fusedRowIDs(results))
+
+	// Rows of different collections are kept apart
+	other := rankedList([]int64{1}, []float64{0.7})
+	other.Results[0].Collection = "other"
+	results, err = Fuse(&FusionOptions{Method: FUSION_RRF, K: 1}, first, other)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 4)
+	assert.ElementsMatch(t, []string{"", "other"}, []string{
+		results.Results[0].Collection,
+		results.Results[1].Collection,
+	})
+
+	_, err = Fuse(&FusionOptions{Method: 9}, first, second)
+	assert.NotNil(t, err)
+	_, err = Fuse(&FusionOptions{Weights: []float64{1}}, first, second)
+	assert.NotNil(t, err)
+	_, err = Fuse(&FusionOptions{Limit: -1}, first, second)
+	assert.NotNil(t, err)
+}
+
+func TestFuseSearches(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db, err := setupSmallDB(sqlite, []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.7, 0.7, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	})
+	require.Nil(t, err)
+
+	options := &FilterOptions{SimilarityOptions: DefaultSimilarityOptions, Limit: 2}
+	x, err := db.Search(&Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}, nil, options)
+	require.Nil(t, err)
+	y, err := db.Search(&Vector{Vector: govector.Vector{0.0, 1.0, 0.0}}, nil, options)
+	require.Nil(t, err)
+
+	// The diagonal vector is second in both, so is fused
+	// into a single result that wins
+	results, err := Fuse(&FusionOptions{Method: FUSION_RRF}, x, y)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{2, 1, 3}, fusedRowIDs(results))
+}
+
+func TestFuseDistanceSearches(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db, err := setupSmallDB(sqlite, []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.9, 0.1, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	})
+	require.Nil(t, err)
+
+	options := &FilterOptions{SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN}}
+	x, err := db.Search(&Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}, nil, options)
+	require.Nil(t, err)
+	assert.True(t, x.Distance)
+	y, err := db.Search(&Vector{Vector: govector.Vector{0.9, 0.1, 0.0}}, nil, options)
+	require.Nil(t, err)
+
+	// Distances are flipped before normalizing, so the
+	// nearest rows still win, and the far row is last
+	for _, method := range []int{FUSION_COMBSUM, FUSION_COMBMNZ} {
+		results, err := Fuse(&FusionOptions{Method: method}, x)
+		require.Nil(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, fusedRowIDs(results))
+		assert.False(t, results.Distance)
+
+		results, err = Fuse(&FusionOptions{Method: method}, x, y)
+		require.Nil(t, err)
+		require.Len(t, results.Results, 3)
+		assert.ElementsMatch(t, []int64{1, 2}, fusedRowIDs(results)[0:2])
+		assert.Equal(t, int64(3), results.Results[2].Vector.RowID)
+	}
+}
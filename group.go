@@ -0,0 +1,70 @@
+package gsvt
+
+import "fmt"
+
+// SearchGroup is the set of best results that share a value
+// for the FilterOptions.GroupBy column.
+type SearchGroup struct {
+	Value interface{}
+
+	// Results are the group's members, best first
+	Results []*SearchResult
+}
+
+// Score is the best score within the group
+func (g *SearchGroup) Score() float64 {
+	return g.Results[0].Score
+}
+
+func (db *DB) validateGroupBy(options *FilterOptions) error {
+	if options.GroupBy == "" {
+		return nil
+	}
+	if options.After != nil {
+		return fmt.Errorf("grouped results can not be paged with a cursor")
+	}
+	if options.GroupSize < 0 {
+		return fmt.Errorf("group size can not be negative")
+	}
+	return db.validateKeyColumn(options.GroupBy)
+}
+
+// groupResults walks the sorted results, keeping at most
+// size results per distinct value of the column. Since the
+// results are sorted, groups are created in order of their
+// best score. If limit is not 0 only that many groups are
+// kept. It returns the groups and their members flattened
+// back into score order.
+func groupResults(results []*SearchResult, column string, size int, limit int) ([]*SearchGroup, []*SearchResult) {
+	if size == 0 {
+		size = 1
+	}
+
+	groups := []*SearchGroup{}
+	groupsByValue := map[interface{}]*SearchGroup{}
+	kept := []*SearchResult{}
+	for _, result := range results {
+		value := result.Vector.Metadata[column]
+
+		group, ok := groupsByValue[value]
+		if !ok {
+			if limit > 0 && len(groups) >= limit {
+				continue
+			}
+			group = &SearchGroup{
+				Value:   value,
+				Results: []*SearchResult{},
+			}
+			groupsByValue[value] = group
+			groups = append(groups, group)
+		}
+
+		if len(group.Results) >= size {
+			continue
+		}
+		group.Results = append(group.Results, result)
+		kept = append(kept, result)
+	}
+
+	return groups, kept
+}
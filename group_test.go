@@ -0,0 +1,80 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchGroupBy(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Document "a" has many strong matches which would
+	// otherwise crowd out everything else
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}, Metadata: map[string]interface{}{"category": "a"}},
+		{Vector: govector.Vector{1.0, 0.05, 0.0}, Metadata: map[string]interface{}{"category": "a"}},
+		{Vector: govector.Vector{1.0, 0.1, 0.0}, Metadata: map[string]interface{}{"category": "a"}},
+		{Vector: govector.Vector{1.0, 0.2, 0.0}, Metadata: map[string]interface{}{"category": "a"}},
+		{Vector: govector.Vector{1.0, 0.5, 0.0}, Metadata: map[string]interface{}{"category": "b"}},
+		{Vector: govector.Vector{1.0, 0.6, 0.0}, Metadata: map[string]interface{}{"category": "b"}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}, Metadata: map[string]interface{}{"category": "c"}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		GroupBy:           "category",
+		GroupSize:         2,
+	})
+	require.Nil(t, err)
+
+	// Each group is ordered by its best score and capped
+	// at two members
+	require.Len(t, results.Groups, 3)
+	assert.Equal(t, "a", results.Groups[0].Value)
+	assert.Equal(t, "b", results.Groups[1].Value)
+	assert.Equal(t, "c", results.Groups[2].Value)
+	assert.Len(t, results.Groups[0].Results, 2)
+	assert.Len(t, results.Groups[1].Results, 2)
+	assert.Len(t, results.Groups[2].Results, 1)
+	assert.Equal(t, vectors[0].RowID, results.Groups[0].Results[0].Vector.RowID)
+	assert.Equal(t, vectors[1].RowID, results.Groups[0].Results[1].Vector.RowID)
+	assert.Greater(t, results.Groups[0].Score(), results.Groups[1].Score())
+
+	// The flattened results are only the kept members
+	require.Len(t, results.Results, 5)
+	for index := 1; index < len(results.Results); index++ {
+		assert.GreaterOrEqual(t, results.Results[index-1].Score, results.Results[index].Score)
+	}
+
+	// The limit applies to the number of groups
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Limit:             2,
+		GroupBy:           "category",
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Groups, 2)
+	require.Len(t, results.Results, 2)
+	assert.Len(t, results.Groups[0].Results, 1)
+
+	// Paging grouped results is not supported, nor is
+	// grouping on the vector
+	_, err = db.Search(target, nil, &FilterOptions{
+		GroupBy: "category",
+		After:   results.Cursor,
+	})
+	assert.NotNil(t, err)
+	_, err = db.Search(target, nil, &FilterOptions{
+		GroupBy: VECTOR_COLUMN_NAME,
+	})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,182 @@
+package gsvt
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MATRYOSHKA_CANDIDATES is how many first stage candidates
+// are rescored if MatryoshkaOptions.Candidates is 0
+const MATRYOSHKA_CANDIDATES = 100
+
+// MatryoshkaOptions configure a two stage search for
+// embeddings whose leading dimensions are usable on their
+// own, as with Matryoshka representation learning.
+type MatryoshkaOptions struct {
+	// Dimensions is how many of the leading dimensions every
+	// candidate is first scored on. Only this prefix of each
+	// candidate's blob is read and decoded.
+	Dimensions int
+
+	// Candidates is how many of the best first stage
+	// candidates are rescored on their full vectors; the
+	// rest are dropped. If 0, MATRYOSHKA_CANDIDATES are
+	// rescored. As no more than this many results can be
+	// returned, FilterOptions.Limit can not exceed it. Facet
+	// candidates are still counted over every first stage
+	// candidate.
+	Candidates int
+}
+
+// candidates returns how many first stage candidates are
+// rescored
+func (m *MatryoshkaOptions) candidates() int {
+	if m.Candidates == 0 {
+		return MATRYOSHKA_CANDIDATES
+	}
+	return m.Candidates
+}
+
+func validateMatryoshka(options *FilterOptions, config *VectorConfig) error {
+	matryoshka := options.Matryoshka
+	if matryoshka == nil {
+		return nil
+	}
+	if config.MultiVector {
+		return fmt.Errorf("matryoshka search is not supported for multi-vector fields")
+	}
+	if matryoshka.Dimensions <= 0 || matryoshka.Dimensions > config.Length {
+		return fmt.Errorf(
+			"matryoshka dimensions must be between 1 and the field length %d",
+			config.Length,
+		)
+	}
+	if matryoshka.Candidates < 0 {
+		return fmt.Errorf("matryoshka candidates can not be negative")
+	}
+	if options.Limit > matryoshka.candidates() {
+		return fmt.Errorf(
+			"limit %d exceeds the %d matryoshka candidates rescored",
+			options.Limit,
+			matryoshka.candidates(),
+		)
+	}
+	return nil
+}
+
+// prefixCandidates loads the vectors matching the filter,
+// less any excluded rowids, with only the first dimensions of
+// the field read from each blob.
+func (db *DB) prefixCandidates(filter *Filter, exclude map[int64]bool, field string, config *VectorConfig, dimensions int) ([]*Vector, error) {
+	if err := db.validateQueryFilter(filter); err != nil {
+		return nil, err
+	}
+	size, err := encodingSize(config.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite's substr works in bytes on BLOBs, so we can
+	// slice off the prefix before it is ever read
+	selectClause := fmt.Sprintf("rowid AS %s", ROWID_COLUMN_NAME)
+	fieldColumn := db.fieldColumn(field)
+	for _, column := range db.schema.Columns {
+		if column.Name == fieldColumn {
+			selectClause += fmt.Sprintf(", substr(%s, 1, %d) AS %s", fieldColumn, dimensions*size, fieldColumn)
+		} else {
+			selectClause += ", " + column.Name
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s ", selectClause, db.schema.Name)
+	whereClause, whereValues := buildWhereClause(filter)
+	if whereClause != "" {
+		query += fmt.Sprintf("WHERE %s ", whereClause)
+	}
+
+	rows, err := db.db.Query(query, whereValues...)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := db.rowsToVectors(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := []*Vector{}
+	for _, vector := range vectors {
+		if !exclude[vector.RowID] {
+			kept = append(kept, vector)
+		}
+	}
+	return kept, nil
+}
+
+// matryoshkaSearch scores every candidate on the leading
+// dimensions of the target, then rescores the best of them
+// on the full vectors before ranking as per the options.
+// The options are expected to be resolved and validated.
+func (db *DB) matryoshkaSearch(target *Vector, filter *Filter, options *FilterOptions, config *VectorConfig, exclude map[int64]bool) (*SearchResults, error) {
+	matryoshka := options.Matryoshka
+
+	// First stage - the prefix of each candidate
+	vectors, err := db.prefixCandidates(filter, exclude, options.Field, config, matryoshka.Dimensions)
+	if err != nil {
+		return nil, err
+	}
+	vectors, projected := fieldVectors(vectors, options.Field)
+	prefix := &Vector{Vector: target.Vector[:matryoshka.Dimensions]}
+	coarse, err := prefix.SimilarityToVectorSet(projected, options.SimilarityOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(projected))
+	for index := range order {
+		order[index] = index
+	}
+	distance := isDistanceMetric(options.similarityOptions().Method)
+	sort.Slice(order, func(a int, b int) bool {
+		if coarse[order[a]] != coarse[order[b]] {
+			return (coarse[order[a]] > coarse[order[b]]) != distance
+		}
+		return projected[order[a]].RowID < projected[order[b]].RowID
+	})
+	if candidates := matryoshka.candidates(); candidates < len(order) {
+		order = order[:candidates]
+	}
+
+	// Second stage - the full vectors of the best
+	rowIDs := make([]int64, len(order))
+	for index, position := range order {
+		rowIDs[index] = projected[position].RowID
+	}
+	full := []*Vector{}
+	if len(rowIDs) > 0 {
+		full, err = db.GetByRowID(rowIDs...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	full, projected = fieldVectors(full, options.Field)
+	similarities, err := target.SimilarityToVectorSet(projected, options.SimilarityOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := rankResults(target, full, similarities, nil, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Facet candidates count every vector that matched the
+	// filter, not just those that were rescored
+	for _, facet := range results.Facets {
+		facet.Candidates = map[interface{}]int{}
+		for _, vector := range vectors {
+			facet.Candidates[vector.Metadata[facet.Column]]++
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,155 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatryoshkaSearch(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := NewDB(sqlite, &Schema{}, &VectorConfig{Length: 4})
+	require.Nil(t, db.Migrate())
+	for _, vector := range []govector.Vector{
+		{1, 0, 0, 1},
+		{1, 0.1, 1, 0},
+		{0, 1, 1, 0},
+		{0.9, 0, 1, 0},
+	} {
+		require.Nil(t, db.Insert(&Vector{Vector: vector}))
+	}
+
+	target := &Vector{Vector: govector.Vector{1, 0, 1, 0}}
+	options := &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Matryoshka:        &MatryoshkaOptions{Dimensions: 2, Candidates: 2},
+	}
+
+	// On the first two dimensions rows 1 and 4 are best;
+	// they're then reranked on their full vectors, and row
+	// 2 - second best overall - never makes the cut
+	results, err := db.Search(target, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, int64(4), results.Results[0].Vector.RowID)
+	assert.Equal(t, int64(1), results.Results[1].Vector.RowID)
+	assert.InDelta(t, 0.9986, results.Results[0].Score, 1e-4)
+	// Results hold the full vectors
+	assert.Equal(t, govector.Vector{0.9, 0, 1, 0}, results.Results[0].Vector.Vector)
+
+	// Rescoring every candidate - here, by default - matches
+	// a plain search
+	options.Matryoshka.Candidates = 0
+	results, err = db.Search(target, nil, options)
+	require.Nil(t, err)
+	plain, err := db.Search(target, nil, &FilterOptions{SimilarityOptions: DefaultSimilarityOptions})
+	require.Nil(t, err)
+	assert.Equal(t, fusedRowIDs(plain), fusedRowIDs(results))
+	assert.Equal(t, []int64{4, 2, 1, 3}, fusedRowIDs(results))
+
+	// ...and is available by stored vector too
+	byID, err := db.QuerySimilarityByID(int64(2), nil, options)
+	require.Nil(t, err)
+	assert.Len(t, byID.Results, 3)
+
+	for _, matryoshka := range []*MatryoshkaOptions{
+		{Dimensions: 0},
+		{Dimensions: 5},
+		{Dimensions: 2, Candidates: -1},
+	} {
+		_, err = db.Search(target, nil, &FilterOptions{Matryoshka: matryoshka})
+		assert.NotNil(t, err)
+	}
+	_, err = db.SearchBatch([]*Vector{target}, nil, options)
+	assert.NotNil(t, err)
+	_, err = db.Recommend(&RecommendQuery{
+		PositiveIDs: []int64{1},
+		Strategy:    RECOMMEND_BEST_SCORE,
+	}, nil, options)
+	assert.NotNil(t, err)
+}
+
+func TestMatryoshkaManyCandidates(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := NewDB(sqlite, &Schema{}, &VectorConfig{Length: 2, Encoding: ENCODING_FLOAT32})
+	require.Nil(t, db.Migrate())
+	count := 2*ROWID_BATCH_SIZE + 1
+	for index := 0; index < count; index++ {
+		require.Nil(t, db.Insert(&Vector{Vector: govector.Vector{float64(index), 1}}))
+	}
+
+	// The survivors are reloaded in batches, nearest first
+	// for distances
+	target := &Vector{Vector: govector.Vector{0, 1}}
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN, Workers: 2},
+		Matryoshka:        &MatryoshkaOptions{Dimensions: 1, Candidates: count},
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, count)
+	assert.Equal(t, int64(1), results.Results[0].Vector.RowID)
+	assert.Equal(t, govector.Vector{0, 1}, results.Results[0].Vector.Vector)
+
+	// ...and by default only the best are rescored
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN, Workers: 2},
+		Matryoshka:        &MatryoshkaOptions{Dimensions: 1},
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, MATRYOSHKA_CANDIDATES)
+	assert.Equal(t, []int64{1, 2, 3}, fusedRowIDs(results)[:3])
+}
+
+func TestMatryoshkaFacets(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db, err := setupSmallDB(sqlite, []*Vector{
+		{Metadata: map[string]interface{}{"category": "a"}, Vector: govector.Vector{1, 0, 0}},
+		{Metadata: map[string]interface{}{"category": "a"}, Vector: govector.Vector{0.9, 0.1, 0}},
+		{Metadata: map[string]interface{}{"category": "b"}, Vector: govector.Vector{0, 1, 0}},
+		{Metadata: map[string]interface{}{"category": "b"}, Vector: govector.Vector{0, 0, 1}},
+	})
+	require.Nil(t, err)
+
+	// Only two candidates are rescored, but facet
+	// candidates still count every vector matching the
+	// filter
+	target := &Vector{Vector: govector.Vector{1, 0, 0}}
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Matryoshka:        &MatryoshkaOptions{Dimensions: 2, Candidates: 2},
+		Facets:            []string{"category"},
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, map[interface{}]int{"a": 2}, results.Facets["category"].Results)
+	assert.Equal(t, map[interface{}]int{"a": 2, "b": 2}, results.Facets["category"].Candidates)
+
+	// Asking for more results than are rescored is an error
+	// rather than a silently short page
+	_, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Matryoshka:        &MatryoshkaOptions{Dimensions: 2, Candidates: 2},
+		Limit:             3,
+	})
+	assert.NotNil(t, err)
+	_, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Matryoshka:        &MatryoshkaOptions{Dimensions: 2},
+		Limit:             MATRYOSHKA_CANDIDATES + 1,
+	})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,119 @@
+package gsvt
+
+import (
+	"fmt"
+	"math"
+)
+
+// MMROptions configure Maximal Marginal Relevance reranking.
+// Each result is chosen in turn by maximizing:
+//
+//	Lambda * sim(target, result) - (1 - Lambda) * max(sim(result, chosen))
+//
+// ...where chosen is the set of results already picked, and
+// sim is the configured SimilarityOptions.Method.
+type MMROptions struct {
+	// Lambda balances relevance against diversity; 1 is
+	// purely relevance (the original order) and 0 is
+	// purely diversity. It must be within [0, 1].
+	Lambda float64
+
+	// Candidates is how many of the top results are
+	// reranked. Results beyond it are left in score order
+	// after the reranked results. If 0, all results are
+	// reranked.
+	Candidates int
+}
+
+var DefaultMMROptions *MMROptions = &MMROptions{
+	Lambda:     0.5,
+	Candidates: 0,
+}
+
+func validateMMR(options *FilterOptions) error {
+	if options.MMR == nil {
+		return nil
+	}
+	if options.After != nil {
+		return fmt.Errorf("mmr reranked results can not be paged with a cursor")
+	}
+	if options.MMR.Lambda < 0 || options.MMR.Lambda > 1 {
+		return fmt.Errorf("mmr lambda %f must be between 0 and 1", options.MMR.Lambda)
+	}
+	if options.MMR.Candidates < 0 {
+		return fmt.Errorf("mmr candidates can not be negative")
+	}
+	return nil
+}
+
+// mmrRerank reorders the (sorted) results via MMR. The
+// result's Score is used as its similarity to the target,
+// and results are compared by the vector field searched.
+// Distances, both the Score if distance is set and those
+// between results, are negated so that higher is better.
+func mmrRerank(results []*SearchResult, mmr *MMROptions, similarityOptions *SimilarityOptions, field string, distance bool) ([]*SearchResult, error) {
+	relevanceSign := 1.0
+	if distance {
+		relevanceSign = -1
+	}
+	similaritySign := 1.0
+	if similarityOptions != nil && isDistanceMetric(similarityOptions.Method) {
+		similaritySign = -1
+	}
+
+	candidates := results
+	rest := []*SearchResult{}
+	if mmr.Candidates > 0 && mmr.Candidates < len(results) {
+		candidates = results[0:mmr.Candidates]
+		rest = results[mmr.Candidates:]
+	}
+
+	// maxSimilarity tracks each remaining candidate's
+	// highest similarity to any chosen result so far; we
+	// only need to update it against the latest choice
+	remaining := append([]*SearchResult{}, candidates...)
+	maxSimilarity := make([]float64, len(remaining))
+	for index := range maxSimilarity {
+		maxSimilarity[index] = math.Inf(-1)
+	}
+
+	reranked := make([]*SearchResult, 0, len(results))
+	for len(remaining) > 0 {
+		best := 0
+		bestScore := math.Inf(-1)
+		for index, candidate := range remaining {
+			// Nothing chosen yet means no redundancy
+			redundancy := 0.0
+			if len(reranked) > 0 {
+				redundancy = maxSimilarity[index]
+			}
+
+			score := mmr.Lambda*relevanceSign*candidate.Score - (1-mmr.Lambda)*redundancy
+			if score > bestScore {
+				best = index
+				bestScore = score
+			}
+		}
+
+		chosen := remaining[best]
+		reranked = append(reranked, chosen)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		maxSimilarity = append(maxSimilarity[:best], maxSimilarity[best+1:]...)
+
+		// Update the redundancy of what remains against
+		// the newly chosen result
+		for index, candidate := range remaining {
+			chosenVector := &Vector{Vector: chosen.Vector.Field(field)}
+			similarity, err := chosenVector.SimilarityToVector(&Vector{Vector: candidate.Vector.Field(field)}, similarityOptions)
+			if err != nil {
+				return nil, err
+			}
+			similarity *= similaritySign
+			if similarity > maxSimilarity[index] {
+				maxSimilarity[index] = similarity
+			}
+		}
+	}
+
+	return append(reranked, rest...), nil
+}
@@ -0,0 +1,104 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchMMR(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// The first two vectors are near duplicates; the third
+	// is a little less relevant but far more distinct
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.1, 0.0}},
+		{Vector: govector.Vector{1.0, 0.11, 0.0}},
+		{Vector: govector.Vector{1.0, 0.0, 0.6}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+
+	// Without MMR the duplicates take the top two spots
+	plain, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+	})
+	require.Nil(t, err)
+	require.Len(t, plain.Results, 3)
+	assert.Equal(t, vectors[0].RowID, plain.Results[0].Vector.RowID)
+	assert.Equal(t, vectors[1].RowID, plain.Results[1].Vector.RowID)
+
+	// With MMR the distinct vector is pulled up
+	diverse, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		MMR:               DefaultMMROptions,
+	})
+	require.Nil(t, err)
+	require.Len(t, diverse.Results, 3)
+	assert.Equal(t, vectors[0].RowID, diverse.Results[0].Vector.RowID)
+	assert.Equal(t, vectors[2].RowID, diverse.Results[1].Vector.RowID)
+	assert.Equal(t, vectors[1].RowID, diverse.Results[2].Vector.RowID)
+
+	// A lambda of 1 is pure relevance, and should match
+	// the original ordering
+	relevant, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		MMR:               &MMROptions{Lambda: 1},
+	})
+	require.Nil(t, err)
+	for index, result := range relevant.Results {
+		assert.Equal(t, plain.Results[index].Vector.RowID, result.Vector.RowID)
+	}
+
+	// Bad lambdas are rejected
+	_, err = db.Search(target, nil, &FilterOptions{
+		MMR: &MMROptions{Lambda: 1.5},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSearchMMRAfterReranker(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+
+	// The reranker scores are higher is better even on a
+	// EUCLIDEAN search, so a pure relevance MMR must keep
+	// the reranked order rather than reversing it
+	reranked, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		Reranker:          &reverseReranker{},
+	})
+	require.Nil(t, err)
+	require.Len(t, reranked.Results, 3)
+
+	diverse, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		Reranker:          &reverseReranker{},
+		MMR:               &MMROptions{Lambda: 1},
+	})
+	require.Nil(t, err)
+	require.Len(t, diverse.Results, 3)
+	for index, result := range diverse.Results {
+		assert.Equal(t, reranked.Results[index].Vector.RowID, result.Vector.RowID)
+	}
+	assert.Equal(t, vectors[0].RowID, diverse.Results[2].Vector.RowID)
+}
@@ -0,0 +1,84 @@
+package gsvt
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/drewlanenga/govector"
+)
+
+// PackMultiVector packs a set of equal length vectors, ie a
+// document's token embeddings, into the single row-major
+// vector a multi-vector field stores.
+func PackMultiVector(vectors []govector.Vector) (govector.Vector, error) {
+	packed := govector.Vector{}
+	for _, vector := range vectors {
+		if len(vector) != len(vectors[0]) {
+			return nil, fmt.Errorf(
+				"multi-vector rows must share a length; got %d and %d",
+				len(vectors[0]),
+				len(vector),
+			)
+		}
+		packed = append(packed, vector...)
+	}
+	return packed, nil
+}
+
+// UnpackMultiVector splits a packed multi-vector back into
+// its rows of the given length.
+func UnpackMultiVector(packed govector.Vector, length int) ([]govector.Vector, error) {
+	if length <= 0 || len(packed)%length != 0 {
+		return nil, fmt.Errorf(
+			"multi-vector of length %d can not be split into rows of length %d",
+			len(packed),
+			length,
+		)
+	}
+
+	rows := make([]govector.Vector, 0, len(packed)/length)
+	for start := 0; start < len(packed); start += length {
+		rows = append(rows, packed[start:start+length])
+	}
+	return rows, nil
+}
+
+// maxSim scores late interaction between two packed
+// multi-vectors; for each of our rows we find its best
+// similarity, by options.Method, to any of the other's rows,
+// and sum them. For distance metrics the best is the lowest.
+func (v *Vector) maxSim(other *Vector, options *SimilarityOptions) (float64, error) {
+	queryRows, err := UnpackMultiVector(v.Vector, options.MultiVectorLength)
+	if err != nil {
+		return 0, err
+	}
+	documentRows, err := UnpackMultiVector(other.Vector, options.MultiVectorLength)
+	if err != nil {
+		return 0, err
+	}
+
+	rowOptions := *options
+	rowOptions.MaxSim = false
+	distance := isDistanceMetric(options.Method)
+
+	total := 0.0
+	for _, queryRow := range queryRows {
+		query := &Vector{Vector: queryRow}
+		best := math.Inf(-1)
+		if distance {
+			best = math.Inf(1)
+		}
+		for _, documentRow := range documentRows {
+			similarity, err := query.SimilarityToVector(&Vector{Vector: documentRow}, &rowOptions)
+			if err != nil {
+				return 0, err
+			}
+			if (distance && similarity < best) || (!distance && similarity > best) {
+				best = similarity
+			}
+		}
+		total += best
+	}
+
+	return total, nil
+}
@@ -0,0 +1,117 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackMultiVector(t *testing.T) {
+	packed, err := PackMultiVector([]govector.Vector{{1, 2}, {3, 4}, {5, 6}})
+	require.Nil(t, err)
+	assert.Equal(t, govector.Vector{1, 2, 3, 4, 5, 6}, packed)
+
+	rows, err := UnpackMultiVector(packed, 2)
+	require.Nil(t, err)
+	assert.Equal(t, []govector.Vector{{1, 2}, {3, 4}, {5, 6}}, rows)
+
+	_, err = PackMultiVector([]govector.Vector{{1, 2}, {3}})
+	assert.NotNil(t, err)
+	_, err = UnpackMultiVector(packed, 4)
+	assert.NotNil(t, err)
+	_, err = UnpackMultiVector(packed, 0)
+	assert.NotNil(t, err)
+}
+
+func TestMaxSim(t *testing.T) {
+	query := &Vector{Vector: govector.Vector{1, 0, 0, 1}}
+	documents := []*Vector{
+		{Vector: govector.Vector{1, 0, 0, 1}},
+		{Vector: govector.Vector{2, 0}},
+		{Vector: govector.Vector{0, 1, 0, 1, 0.5, 0.5}},
+	}
+	options := &SimilarityOptions{
+		Method:            DOT_PRODUCT,
+		Workers:           2,
+		MaxSim:            true,
+		MultiVectorLength: 2,
+	}
+
+	similarities, err := query.SimilarityToVectorSet(documents, options)
+	require.Nil(t, err)
+	assert.Equal(t, []float64{2, 2, 1.5}, similarities)
+
+	// Errors from the worker pool are returned
+	documents = append(documents, &Vector{Vector: govector.Vector{1, 2, 3}})
+	_, err = query.SimilarityToVectorSet(documents, options)
+	assert.NotNil(t, err)
+}
+
+func TestMultiVectorField(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := NewDBWithFields(sqlite, &Schema{Name: "passages"}, map[string]*VectorConfig{
+		"tokens": {Length: 2, MultiVector: true, Encoding: ENCODING_FLOAT32},
+	})
+	require.Nil(t, db.Migrate())
+
+	passages := [][]govector.Vector{
+		{{1, 0}, {0, 1}},
+		{{1, 0}},
+		{{0, 1}, {0, 1}, {0, 1}},
+	}
+	for _, tokens := range passages {
+		packed, err := PackMultiVector(tokens)
+		require.Nil(t, err)
+		require.Nil(t, db.Insert(&Vector{Fields: map[string]govector.Vector{"tokens": packed}}))
+	}
+
+	// Rows must be a whole number of tokens
+	assert.NotNil(t, db.Insert(&Vector{Fields: map[string]govector.Vector{"tokens": {1, 0, 1}}}))
+
+	query, err := PackMultiVector([]govector.Vector{{1, 0}, {0, 1}})
+	require.Nil(t, err)
+	options := &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: DOT_PRODUCT, Workers: 2},
+		Field:             "tokens",
+	}
+	results, err := db.Search(&Vector{Vector: query}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+	assert.Equal(t, int64(1), results.Results[0].Vector.RowID)
+	assert.Equal(t, 2.0, results.Results[0].Score)
+	assert.Equal(t, 1.0, results.Results[1].Score)
+	assert.Equal(t, 1.0, results.Results[2].Score)
+
+	// Normalizing averages MaxSim over the query's rows, so
+	// only the exact match is fully relevant
+	options.SimilarityOptions = &SimilarityOptions{Method: COSINE, Workers: 2}
+	options.Normalize = true
+	results, err = db.Search(&Vector{Vector: query}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+	assert.Equal(t, 1.0, results.Results[0].Relevance)
+	assert.Equal(t, 0.75, results.Results[1].Relevance)
+	assert.Equal(t, 0.75, results.Results[2].Relevance)
+	options.SimilarityOptions = &SimilarityOptions{Method: DOT_PRODUCT, Workers: 2}
+	options.Normalize = false
+
+	_, err = db.Search(&Vector{Vector: govector.Vector{1}}, nil, options)
+	assert.NotNil(t, err)
+
+	// Multi-vectors can't be averaged, but can recommend
+	// by their best score
+	_, err = db.Recommend(&RecommendQuery{PositiveIDs: []int64{2}}, nil, options)
+	assert.NotNil(t, err)
+	recommended, err := db.Recommend(&RecommendQuery{
+		PositiveIDs: []int64{2},
+		Strategy:    RECOMMEND_BEST_SCORE,
+	}, nil, options)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), recommended.Results[0].Vector.RowID)
+}
@@ -0,0 +1,131 @@
+package gsvt
+
+import (
+	"math"
+
+	"github.com/drewlanenga/govector"
+)
+
+// NormStatistics describe the L2 norms of a set of vectors.
+// They're used to calibrate DOT_PRODUCT scores, which are
+// otherwise unbounded.
+type NormStatistics struct {
+	Count int
+	Min   float64
+	Max   float64
+	Mean  float64
+}
+
+// NormStatistics calculates the norm statistics of all
+// vectors matching the filter, for the field selected by the
+// options as in Search; each row of a multi-vector field is
+// counted as a vector. If options is nil the default field
+// is used. This loads every matching vector, so it's best
+// calculated once and reused.
+func (db *DB) NormStatistics(filter *Filter, options *FilterOptions) (*NormStatistics, error) {
+	if options == nil {
+		options = &DefaultFilterOptions
+	}
+	options, config, err := db.fieldOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors, err := db.Query(filter)
+	if err != nil {
+		return nil, err
+	}
+	_, projected := fieldVectors(vectors, options.Field)
+
+	norms := []float64{}
+	for _, vector := range projected {
+		rows := []govector.Vector{vector.Vector}
+		if config.MultiVector {
+			rows, err = UnpackMultiVector(vector.Vector, config.Length)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, row := range rows {
+			norms = append(norms, govector.Norm(row, 2))
+		}
+	}
+
+	stats := &NormStatistics{
+		Count: len(norms),
+	}
+	if len(norms) == 0 {
+		return stats, nil
+	}
+
+	stats.Min = math.Inf(1)
+	stats.Max = math.Inf(-1)
+	for _, norm := range norms {
+		stats.Min = math.Min(stats.Min, norm)
+		stats.Max = math.Max(stats.Max, norm)
+		stats.Mean += norm
+	}
+	stats.Mean /= float64(len(norms))
+
+	return stats, nil
+}
+
+// similarityOptions returns the options' SimilarityOptions,
+// or the defaults if they're not set
+func (o *FilterOptions) similarityOptions() *SimilarityOptions {
+	if o.SimilarityOptions == nil {
+		return DefaultSimilarityOptions
+	}
+	return o.SimilarityOptions
+}
+
+// normalizeRelevance normalizes a result's raw similarity to
+// the target. MaxSim sums a similarity per query row, so it's
+// first averaged over the target's rows to bring it back to
+// the range of a single similarity.
+func normalizeRelevance(options *SimilarityOptions, score float64, target *Vector, stats *NormStatistics) float64 {
+	if options.MaxSim && options.MultiVectorLength > 0 && target != nil {
+		rows := len(target.Vector) / options.MultiVectorLength
+		if rows > 0 {
+			score /= float64(rows)
+		}
+	}
+	return normalizeScore(options.Method, score, target, stats)
+}
+
+// normalizeScore maps a raw score to [0, 1], where 1 is the
+// most relevant, for the given metric:
+//
+// COSINE - scores within [-1, 1] are mapped linearly.
+//
+// EUCLIDEAN - distances within [0, inf) are mapped to
+// 1 / (1 + distance).
+//
+// DOT_PRODUCT - if norm statistics and the target are
+// available, the score is bounded by the Cauchy-Schwarz
+// inequality to within +/- |target| * max norm, and mapped
+// linearly. Otherwise the score is passed through a
+// logistic sigmoid.
+func normalizeScore(method int, score float64, target *Vector, stats *NormStatistics) float64 {
+	var normalized float64
+	switch method {
+	case EUCLIDEAN:
+		normalized = 1 / (1 + math.Max(score, 0))
+	case DOT_PRODUCT:
+		bound := 0.0
+		if stats != nil && target != nil {
+			bound = govector.Norm(target.Vector, 2) * stats.Max
+		}
+		if bound > 0 {
+			normalized = (score/bound + 1) / 2
+		} else {
+			normalized = sigmoid(score)
+		}
+	default:
+		normalized = (score + 1) / 2
+	}
+
+	// Floating point error (or a stale set of statistics)
+	// can push us slightly out of bounds
+	return math.Max(0, math.Min(1, normalized))
+}
@@ -0,0 +1,126 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeScore(t *testing.T) {
+	assert.Equal(t, 1.0, normalizeScore(COSINE, 1, nil, nil))
+	assert.Equal(t, 0.5, normalizeScore(COSINE, 0, nil, nil))
+	assert.Equal(t, 0.0, normalizeScore(COSINE, -1, nil, nil))
+
+	assert.Equal(t, 1.0, normalizeScore(EUCLIDEAN, 0, nil, nil))
+	assert.Equal(t, 0.5, normalizeScore(EUCLIDEAN, 1, nil, nil))
+
+	// Without statistics dot products go through a sigmoid
+	assert.Equal(t, 0.5, normalizeScore(DOT_PRODUCT, 0, nil, nil))
+	assert.Greater(t, normalizeScore(DOT_PRODUCT, 5, nil, nil), normalizeScore(DOT_PRODUCT, 2, nil, nil))
+
+	// With them, they're bounded by the norms
+	target := &Vector{Vector: govector.Vector{2.0, 0.0}}
+	stats := &NormStatistics{Count: 2, Min: 1, Max: 3, Mean: 2}
+	assert.Equal(t, 1.0, normalizeScore(DOT_PRODUCT, 6, target, stats))
+	assert.Equal(t, 0.75, normalizeScore(DOT_PRODUCT, 3, target, stats))
+	assert.Equal(t, 0.0, normalizeScore(DOT_PRODUCT, -6, target, stats))
+	assert.Equal(t, 1.0, normalizeScore(DOT_PRODUCT, 7, target, stats))
+}
+
+func TestSearchNormalize(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{3.0, 4.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, -2.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	stats, err := db.NormStatistics(nil, nil)
+	require.Nil(t, err)
+	assert.Equal(t, 3, stats.Count)
+	assert.Equal(t, 1.0, stats.Min)
+	assert.Equal(t, 5.0, stats.Max)
+	assert.Equal(t, 8.0/3.0, stats.Mean)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	for _, method := range []int{COSINE, DOT_PRODUCT} {
+		results, err := db.Search(target, nil, &FilterOptions{
+			SimilarityOptions: &SimilarityOptions{Method: method},
+			Normalize:         true,
+			NormStatistics:    stats,
+		})
+		require.Nil(t, err)
+		require.Len(t, results.Results, len(vectors))
+
+		// Relevance should follow the ordering of the raw
+		// scores, within [0, 1]
+		for index, result := range results.Results {
+			assert.GreaterOrEqual(t, result.Relevance, 0.0)
+			assert.LessOrEqual(t, result.Relevance, 1.0)
+			if index > 0 {
+				assert.LessOrEqual(t, result.Relevance, results.Results[index-1].Relevance)
+			}
+		}
+	}
+
+	// If not requested, relevance is left unset
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+	})
+	require.Nil(t, err)
+	for _, result := range results.Results {
+		assert.Equal(t, 0.0, result.Relevance)
+	}
+}
+
+func TestNormStatisticsFields(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := NewDBWithFields(sqlite, &Schema{Name: "passages"}, map[string]*VectorConfig{
+		VECTOR_COLUMN_NAME: {Length: 2},
+		"summary":          {Length: 3},
+		"tokens":           {Length: 2, MultiVector: true},
+	})
+	require.Nil(t, db.Migrate())
+
+	tokens, err := PackMultiVector([]govector.Vector{{3, 4}, {0, 2}})
+	require.Nil(t, err)
+	require.Nil(t, db.Insert(&Vector{
+		Vector: govector.Vector{1, 0},
+		Fields: map[string]govector.Vector{"summary": {0, 0, 10}, "tokens": tokens},
+	}))
+	require.Nil(t, db.Insert(&Vector{
+		Vector: govector.Vector{0, 2},
+		Fields: map[string]govector.Vector{"summary": {0, 6, 8}},
+	}))
+
+	// The default field
+	stats, err := db.NormStatistics(nil, nil)
+	require.Nil(t, err)
+	assert.Equal(t, &NormStatistics{Count: 2, Min: 1, Max: 2, Mean: 1.5}, stats)
+
+	// A named field
+	stats, err = db.NormStatistics(nil, &FilterOptions{Field: "summary"})
+	require.Nil(t, err)
+	assert.Equal(t, &NormStatistics{Count: 2, Min: 10, Max: 10, Mean: 10}, stats)
+
+	// Each row of a multi-vector counts, and rows without
+	// the field are skipped
+	stats, err = db.NormStatistics(nil, &FilterOptions{Field: "tokens"})
+	require.Nil(t, err)
+	assert.Equal(t, &NormStatistics{Count: 2, Min: 2, Max: 5, Mean: 3.5}, stats)
+
+	_, err = db.NormStatistics(nil, &FilterOptions{Field: "missing"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,216 @@
+package gsvt
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/drewlanenga/govector"
+)
+
+// Strategies for combining examples within a Recommend
+const RECOMMEND_AVERAGE = 0
+const RECOMMEND_BEST_SCORE = 1
+
+// RecommendQuery describes a "more like these, less like
+// those" search. Examples can be given as vectors, as the
+// rowids of stored vectors, or both.
+type RecommendQuery struct {
+	Positive    []*Vector
+	PositiveIDs []int64
+	Negative    []*Vector
+	NegativeIDs []int64
+
+	// Strategy is one of:
+	//
+	// RECOMMEND_AVERAGE - searches with a single target of
+	// avg(positive) + (avg(positive) - avg(negative)), or
+	// just avg(positive) if there are no negatives.
+	//
+	// RECOMMEND_BEST_SCORE - scores each candidate by its
+	// best similarity to any positive, passed through a
+	// logistic sigmoid so that it is always positive. If a
+	// candidate is more similar to a negative than any
+	// positive, it is instead scored as the negated sigmoid
+	// of that similarity, so that it always ranks below
+	// those closer to a positive. It requires a similarity
+	// metric, not EUCLIDEAN.
+	Strategy int
+}
+
+// Recommend searches for vectors similar to the positive
+// examples and dissimilar to the negative examples. Stored
+// examples - those given by rowid or with a RowID set - are
+// never returned. Otherwise it behaves exactly as Search.
+func (db *DB) Recommend(query *RecommendQuery, filter *Filter, options *FilterOptions) (*SearchResults, error) {
+	positives, negatives, exclude, err := db.recommendExamples(query, db.fieldName(options))
+	if err != nil {
+		return nil, err
+	}
+
+	switch query.Strategy {
+	case RECOMMEND_AVERAGE:
+		target, err := averageTarget(positives, negatives)
+		if err != nil {
+			return nil, err
+		}
+		return db.search(target, nil, filter, options, exclude)
+	case RECOMMEND_BEST_SCORE:
+		// Negatives are scored by negating their similarity,
+		// which has no meaning for distances
+		resolved := options
+		if resolved == nil {
+			resolved = &DefaultFilterOptions
+		}
+		resolved, _, err := db.fieldOptions(resolved)
+		if err != nil {
+			return nil, err
+		}
+		if isDistanceMetric(resolved.similarityOptions().Method) {
+			return nil, fmt.Errorf("RECOMMEND_BEST_SCORE requires a similarity metric")
+		}
+		scorer := bestScoreScorer(positives, negatives)
+		return db.search(nil, scorer, filter, options, exclude)
+	default:
+		return nil, fmt.Errorf("unknown recommend strategy %d", query.Strategy)
+	}
+}
+
+// recommendExamples gathers the positive and negative
+// examples, loading any given by rowid, and notes which
+// rowids are to be excluded from the results. Examples
+// loaded by rowid use their value for the field searched.
+func (db *DB) recommendExamples(query *RecommendQuery, field string) ([]*Vector, []*Vector, map[int64]bool, error) {
+	config, err := db.field(field)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if config.MultiVector && query.Strategy == RECOMMEND_AVERAGE {
+		return nil, nil, nil, fmt.Errorf("multi-vector field %s can not be averaged; use RECOMMEND_BEST_SCORE", field)
+	}
+
+	positives := append([]*Vector{}, query.Positive...)
+	negatives := append([]*Vector{}, query.Negative...)
+
+	if len(query.PositiveIDs) > 0 {
+		stored, err := db.GetByRowID(query.PositiveIDs...)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		_, stored = fieldVectors(stored, field)
+		positives = append(positives, stored...)
+	}
+	if len(query.NegativeIDs) > 0 {
+		stored, err := db.GetByRowID(query.NegativeIDs...)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		_, stored = fieldVectors(stored, field)
+		negatives = append(negatives, stored...)
+	}
+
+	if len(positives) == 0 {
+		return nil, nil, nil, fmt.Errorf("at least one positive example is required")
+	}
+
+	exclude := map[int64]bool{}
+	for _, examples := range [][]*Vector{positives, negatives} {
+		for _, example := range examples {
+			if err := validateFieldVector(field, config, example.Vector); err != nil {
+				return nil, nil, nil, err
+			}
+			if example.RowID != 0 {
+				exclude[example.RowID] = true
+			}
+		}
+	}
+
+	return positives, negatives, exclude, nil
+}
+
+// averageTarget builds the single target vector for the
+// RECOMMEND_AVERAGE strategy
+func averageTarget(positives []*Vector, negatives []*Vector) (*Vector, error) {
+	target := averageVector(positives)
+	if len(negatives) > 0 {
+		negative := averageVector(negatives)
+
+		// target + (target - negative)
+		difference, err := target.Subtract(negative)
+		if err != nil {
+			return nil, err
+		}
+		for index := range target {
+			target[index] += difference[index]
+		}
+	}
+
+	return &Vector{Vector: target}, nil
+}
+
+func averageVector(vectors []*Vector) govector.Vector {
+	average := make(govector.Vector, len(vectors[0].Vector))
+	for _, vector := range vectors {
+		for index, value := range vector.Vector {
+			average[index] += value
+		}
+	}
+	for index := range average {
+		average[index] /= float64(len(vectors))
+	}
+	return average
+}
+
+// bestScoreScorer creates a scorer for the
+// RECOMMEND_BEST_SCORE strategy. Each example is compared
+// against all candidates on the worker pool in turn. The
+// sigmoid keeps positive scores above zero and negative ones
+// below, whatever the sign of the similarities.
+func bestScoreScorer(positives []*Vector, negatives []*Vector) vectorScorer {
+	return func(candidates []*Vector, options *SimilarityOptions) ([]float64, error) {
+		bestPositive, err := bestSimilarities(positives, candidates, options)
+		if err != nil {
+			return nil, err
+		}
+		bestNegative, err := bestSimilarities(negatives, candidates, options)
+		if err != nil {
+			return nil, err
+		}
+
+		scores := make([]float64, len(candidates))
+		for index := range candidates {
+			if len(negatives) == 0 || bestPositive[index] > bestNegative[index] {
+				scores[index] = sigmoid(bestPositive[index])
+			} else {
+				scores[index] = -sigmoid(bestNegative[index])
+			}
+		}
+		return scores, nil
+	}
+}
+
+func sigmoid(value float64) float64 {
+	return 1 / (1 + math.Exp(-value))
+}
+
+// bestSimilarities finds, for each candidate, its highest
+// similarity to any of the examples
+func bestSimilarities(examples []*Vector, candidates []*Vector, options *SimilarityOptions) ([]float64, error) {
+	best := make([]float64, len(candidates))
+	for index := range best {
+		best[index] = math.Inf(-1)
+	}
+
+	for _, example := range examples {
+		similarities, err := example.SimilarityToVectorSet(candidates, options)
+		if err != nil {
+			return nil, err
+		}
+		for index, similarity := range similarities {
+			if similarity > best[index] {
+				best[index] = similarity
+			}
+		}
+	}
+
+	return best, nil
+}
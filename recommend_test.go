@@ -0,0 +1,102 @@
+package gsvt
+
+import (
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommend(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{1.0, 0.2, 0.0}},
+		{Vector: govector.Vector{1.0, 0.0, 0.2}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	options := &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+	}
+
+	for _, strategy := range []int{RECOMMEND_AVERAGE, RECOMMEND_BEST_SCORE} {
+		// Leaning towards the y axis and away from z should
+		// rank the y leaning vector above the z leaning one,
+		// and never return our stored example
+		results, err := db.Recommend(&RecommendQuery{
+			PositiveIDs: []int64{vectors[0].RowID},
+			Positive:    []*Vector{{Vector: govector.Vector{0.5, 0.5, 0.0}}},
+			Negative:    []*Vector{{Vector: govector.Vector{0.0, 0.0, 1.0}}},
+			Strategy:    strategy,
+		}, nil, options)
+		require.Nil(t, err)
+		require.Len(t, results.Results, len(vectors)-1)
+
+		ranks := map[int64]int{}
+		for index, result := range results.Results {
+			ranks[result.Vector.RowID] = index
+		}
+		assert.NotContains(t, ranks, vectors[0].RowID)
+		assert.Less(t, ranks[vectors[1].RowID], ranks[vectors[2].RowID])
+		assert.Less(t, ranks[vectors[3].RowID], ranks[vectors[4].RowID])
+	}
+
+	// Negative examples given by rowid are also excluded
+	results, err := db.Recommend(&RecommendQuery{
+		Positive:    []*Vector{{Vector: govector.Vector{1.0, 0.0, 0.0}}},
+		NegativeIDs: []int64{vectors[4].RowID},
+		Strategy:    RECOMMEND_BEST_SCORE,
+	}, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, len(vectors)-1)
+	for _, result := range results.Results {
+		assert.NotEqual(t, vectors[4].RowID, result.Vector.RowID)
+	}
+
+	// We need at least one positive, and known ids
+	_, err = db.Recommend(&RecommendQuery{
+		NegativeIDs: []int64{vectors[4].RowID},
+	}, nil, options)
+	assert.NotNil(t, err)
+	_, err = db.Recommend(&RecommendQuery{
+		PositiveIDs: []int64{12345},
+	}, nil, options)
+	assert.NotNil(t, err)
+}
+
+func TestRecommendBestScoreNegativeSimilarities(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db, err := setupSmallDB(sqlite, []*Vector{
+		{Vector: govector.Vector{1.0, 0.5, 0.0}},
+		{Vector: govector.Vector{0.2, 0.1, 1.0}},
+		{Vector: govector.Vector{-1.0, -0.5, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+	})
+	require.Nil(t, err)
+
+	// The third vector points away from both examples, so is
+	// "closer" to the negative, and must rank below any
+	// vector closer to the positive
+	results, err := db.Recommend(&RecommendQuery{
+		Positive: []*Vector{{Vector: govector.Vector{1.0, 0.0, 0.0}}},
+		Negative: []*Vector{{Vector: govector.Vector{0.0, 1.0, 0.0}}},
+		Strategy: RECOMMEND_BEST_SCORE,
+	}, nil, &FilterOptions{SimilarityOptions: DefaultSimilarityOptions})
+	require.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4}, fusedRowIDs(results))
+	assert.Greater(t, results.Results[1].Score, 0.0)
+	assert.Less(t, results.Results[2].Score, 0.0)
+}
@@ -0,0 +1,208 @@
+package gsvt
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// REGISTRY_TABLE_NAME is the table each collection is
+// recorded in as it is migrated, so that it can't be opened
+// or migrated with the wrong config.
+const REGISTRY_TABLE_NAME = "_gsvt_collections"
+
+// CollectionInfo is a collection's entry in the registry
+type CollectionInfo struct {
+	Name string
+
+	// Schema is the table as read back once it was created
+	Schema *Schema
+
+	// Fields are the configs of each vector field, keyed by
+	// name, as given on creation
+	Fields map[string]*VectorConfig
+
+	CreatedAt time.Time
+}
+
+// Config returns the config of the collection's default
+// VECTOR_COLUMN_NAME field, or nil if it has none
+func (c *CollectionInfo) Config() *VectorConfig {
+	return c.Fields[VECTOR_COLUMN_NAME]
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so that
+// registry entries can be written within a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func createRegistry(db execer) error {
+	_, err := db.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (`+
+			`name TEXT PRIMARY KEY, schema TEXT NOT NULL, fields TEXT NOT NULL, created_at TIMESTAMP NOT NULL)`,
+		REGISTRY_TABLE_NAME,
+	))
+	return err
+}
+
+// Info returns the registry entry of the named collection,
+// or nil if it was never migrated by gsvt.
+func (s *Store) Info(name string) (*CollectionInfo, error) {
+	return readRegistry(s.db, name)
+}
+
+// readRegistry returns the registry entry of the named
+// collection, or nil if it has none
+func readRegistry(db *sql.DB, name string) (*CollectionInfo, error) {
+	exists, err := tableExists(db, REGISTRY_TABLE_NAME)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := db.Query(
+		fmt.Sprintf(`SELECT schema, fields, created_at FROM %s WHERE name = ?`, REGISTRY_TABLE_NAME),
+		name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	info := &CollectionInfo{Name: name}
+	var schema string
+	var fields string
+	if err := rows.Scan(&schema, &fields, &info.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(schema), &info.Schema); err != nil {
+		return nil, fmt.Errorf("registry entry for %s: %v", name, err)
+	}
+	if err := json.Unmarshal([]byte(fields), &info.Fields); err != nil {
+		return nil, fmt.Errorf("registry entry for %s: %v", name, err)
+	}
+	return info, nil
+}
+
+// writeRegistry records the collection of the given schema,
+// as it is read back from the database so that it can be
+// compared to what is found on opening, replacing any prior
+// entry
+func writeRegistry(db execer, schema *Schema, fields map[string]*VectorConfig, createdAt time.Time) error {
+	if err := createRegistry(db); err != nil {
+		return err
+	}
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		fmt.Sprintf(
+			`INSERT OR REPLACE INTO %s (name, schema, fields, created_at) VALUES (?, ?, ?, ?)`,
+			REGISTRY_TABLE_NAME,
+		),
+		schema.Name,
+		string(schemaJSON),
+		string(fieldsJSON),
+		createdAt.UTC(),
+	)
+	return err
+}
+
+// unregister removes the named collection's entry, if any
+func unregister(db execer, name string) error {
+	if err := createRegistry(db); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE name = ?`, REGISTRY_TABLE_NAME), name)
+	return err
+}
+
+// validateRegistered ensures the collection is opened with
+// the fields it was registered with, and that its table has
+// not since been changed
+func validateRegistered(info *CollectionInfo, schema *Schema, fields map[string]*VectorConfig) error {
+	if len(fields) != len(info.Fields) {
+		return fmt.Errorf("collection %s is registered with the vector fields %v", info.Name, info.fieldNames())
+	}
+	for _, name := range info.fieldNames() {
+		config, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("collection %s is registered with the vector fields %v", info.Name, info.fieldNames())
+		}
+		if err := info.Fields[name].validateMatch(config); err != nil {
+			return fmt.Errorf("collection %s field %s: %v", info.Name, name, err)
+		}
+	}
+
+	if !info.Schema.Equal(schema) {
+		return fmt.Errorf("collection %s no longer matches its registered schema", info.Name)
+	}
+	return nil
+}
+
+// validateMigration ensures each registered field is among
+// the fields being migrated, and configured as registered.
+// Fields may be added, and as Migrate rebuilds posting lists
+// they may be turned on or off.
+func validateMigration(info *CollectionInfo, fields map[string]*VectorConfig) error {
+	for _, name := range info.fieldNames() {
+		config, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("collection %s is registered with the vector fields %v", info.Name, info.fieldNames())
+		}
+		registered := *info.Fields[name]
+		registered.SparsePostings = config.SparsePostings
+		if err := registered.validateMatch(config); err != nil {
+			return fmt.Errorf("collection %s field %s: %v", info.Name, name, err)
+		}
+	}
+	return nil
+}
+
+func (c *CollectionInfo) fieldNames() []string {
+	names := []string{}
+	for name := range c.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateMatch ensures the other config stores and scores
+// vectors just as this, the registered config, does
+func (c *VectorConfig) validateMatch(other *VectorConfig) error {
+	if other == nil {
+		return fmt.Errorf("no config given")
+	}
+	if c.Length != other.Length {
+		return fmt.Errorf("length %d does not match the registered length %d", other.Length, c.Length)
+	}
+	if c.Encoding != other.Encoding {
+		return fmt.Errorf("encoding %d does not match the registered encoding %d", other.Encoding, c.Encoding)
+	}
+	if (c.Method == nil) != (other.Method == nil) || (c.Method != nil && *c.Method != *other.Method) {
+		return fmt.Errorf("method does not match the registered method")
+	}
+	if c.MultiVector != other.MultiVector {
+		return fmt.Errorf("multi-vector setting does not match the registered setting")
+	}
+	if c.Sparse != other.Sparse || c.SparsePostings != other.SparsePostings {
+		return fmt.Errorf("sparse settings do not match the registered settings")
+	}
+	if c.Model != other.Model {
+		return fmt.Errorf("model %q does not match the registered model %q", other.Model, c.Model)
+	}
+	return nil
+}
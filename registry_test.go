@@ -0,0 +1,163 @@
+package gsvt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	store := NewStore(sqlite)
+	dotProduct := DOT_PRODUCT
+	config := &VectorConfig{
+		Length:   3,
+		Method:   &dotProduct,
+		Encoding: ENCODING_FLOAT32,
+		Model:    "text-embedding-small",
+	}
+
+	before := time.Now().Add(-time.Second)
+	db, err := store.Create(storeSchema("articles"), config)
+	require.Nil(t, err)
+	require.Nil(t, db.Insert(&Vector{Vector: govector.Vector{1, 0, 0}}))
+
+	info, err := store.Info("articles")
+	require.Nil(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, config, info.Config())
+	assert.Equal(t, "category", info.Schema.Indexes[0].Name)
+	assert.True(t, info.CreatedAt.After(before))
+
+	// The registered config opens the collection by default
+	opened, err := store.Open("articles", nil)
+	require.Nil(t, err)
+	vectors, err := opened.Query(nil)
+	require.Nil(t, err)
+	assert.Equal(t, govector.Vector{1, 0, 0}, vectors[0].Vector)
+
+	matching := *config
+	_, err = store.Open("articles", &matching)
+	assert.Nil(t, err)
+
+	// ...and any other is refused
+	euclidean := EUCLIDEAN
+	for _, mismatched := range []*VectorConfig{
+		{Length: 4, Method: &dotProduct, Encoding: ENCODING_FLOAT32, Model: "text-embedding-small"},
+		{Length: 3, Method: &euclidean, Encoding: ENCODING_FLOAT32, Model: "text-embedding-small"},
+		{Length: 3, Encoding: ENCODING_FLOAT32, Model: "text-embedding-small"},
+		{Length: 3, Method: &dotProduct, Model: "text-embedding-small"},
+		{Length: 3, Method: &dotProduct, Encoding: ENCODING_FLOAT32, Model: "text-embedding-large"},
+	} {
+		_, err = store.Open("articles", mismatched)
+		assert.NotNil(t, err)
+	}
+	_, err = store.OpenWithFields("articles", map[string]*VectorConfig{
+		VECTOR_COLUMN_NAME: config,
+		"title":            config,
+	})
+	assert.NotNil(t, err)
+
+	// As is a table altered behind the registry's back
+	_, err = sqlite.Exec("ALTER TABLE articles ADD COLUMN extra TEXT")
+	require.Nil(t, err)
+	_, err = store.Open("articles", nil)
+	assert.NotNil(t, err)
+	_, err = sqlite.Exec("ALTER TABLE articles DROP COLUMN extra")
+	require.Nil(t, err)
+
+	// Entries follow their collection
+	require.Nil(t, store.Clone("articles", "archive"))
+	require.Nil(t, store.Rename("articles", "posts"))
+	info, err = store.Info("articles")
+	require.Nil(t, err)
+	assert.Nil(t, info)
+	for _, name := range []string{"archive", "posts"} {
+		info, err = store.Info(name)
+		require.Nil(t, err)
+		require.NotNil(t, info)
+		assert.Equal(t, name, info.Schema.Name)
+		_, err = store.Open(name, nil)
+		assert.Nil(t, err)
+	}
+
+	require.Nil(t, store.Drop("posts"))
+	info, err = store.Info("posts")
+	require.Nil(t, err)
+	assert.Nil(t, info)
+
+	// Unregistered collections need their config given
+	_, err = sqlite.Exec("CREATE TABLE legacy (vector BLOB)")
+	require.Nil(t, err)
+	_, err = store.Open("legacy", nil)
+	assert.NotNil(t, err)
+	_, err = store.Open("legacy", &VectorConfig{Length: 2})
+	assert.Nil(t, err)
+
+	// ...and, never having been migrated, aren't listed
+
+	names, err := store.Collections()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"archive"}, names)
+}
+
+func TestRegistryMigrate(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	store := NewStore(sqlite)
+	config := &VectorConfig{Length: 3, Model: "text-embedding-small"}
+	db, err := store.Create(storeSchema("docs"), config)
+	require.Nil(t, err)
+	info, err := store.Info("docs")
+	require.Nil(t, err)
+	createdAt := info.CreatedAt
+
+	// Adding a field updates the entry, so the collection
+	// opens with it
+	title := &VectorConfig{Length: 2, Encoding: ENCODING_FLOAT32}
+	require.Nil(t, db.AddField("title", title))
+	opened, err := store.Open("docs", nil)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"title", VECTOR_COLUMN_NAME}, opened.Fields())
+	info, err = store.Info("docs")
+	require.Nil(t, err)
+	assert.Equal(t, title, info.Fields["title"])
+	assert.True(t, createdAt.Equal(info.CreatedAt))
+
+	// As does migrating a changed schema
+	changed := storeSchema("docs")
+	changed.Columns = append(changed.Columns, &Column{Name: "author", Type: "TEXT"})
+	require.Nil(t, NewDBWithFields(sqlite, changed, map[string]*VectorConfig{
+		VECTOR_COLUMN_NAME: config,
+		"title":            title,
+	}).Migrate())
+	_, err = store.Open("docs", nil)
+	assert.Nil(t, err)
+
+	// Migrating a registered collection with another config,
+	// or without one of its fields, is refused
+	for _, fields := range []map[string]*VectorConfig{
+		{VECTOR_COLUMN_NAME: {Length: 5, Model: "text-embedding-small"}, "title": title},
+		{VECTOR_COLUMN_NAME: {Length: 3}, "title": title},
+		{VECTOR_COLUMN_NAME: config},
+	} {
+		assert.NotNil(t, NewDBWithFields(sqlite, changed, fields).Migrate())
+	}
+
+	// Plain DBs are registered as they're migrated
+	require.Nil(t, NewDB(sqlite, &Schema{Name: "plain"}, &VectorConfig{Length: 2}).Migrate())
+	opened, err = store.Open("plain", nil)
+	require.Nil(t, err)
+	assert.Equal(t, 2, opened.config.Length)
+	assert.NotNil(t, NewDB(sqlite, &Schema{Name: "plain"}, &VectorConfig{Length: 5}).Migrate())
+}
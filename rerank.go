@@ -0,0 +1,160 @@
+package gsvt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Reranker is a second stage of ranking, ie a cross-encoder,
+// given the top results of a search with their metadata.
+type Reranker interface {
+	// Rerank returns a new score for each of the results,
+	// sharing their indexes. The results are ordered best
+	// first by their current score.
+	Rerank(results []*SearchResult) ([]float64, error)
+}
+
+func validateReranker(options *FilterOptions) error {
+	if options.Reranker == nil {
+		return nil
+	}
+	if options.After != nil {
+		return fmt.Errorf("reranked results can not be paged with a cursor")
+	}
+	if options.RerankTop < 0 {
+		return fmt.Errorf("rerank top can not be negative")
+	}
+	return nil
+}
+
+// rerankResults rescores the top results with the reranker
+// and sorts them by their new score. Ties keep their prior
+// order. Results beyond top are left as they were, after
+// the reranked results.
+func rerankResults(results []*SearchResult, reranker Reranker, top int) ([]*SearchResult, error) {
+	if top <= 0 || top > len(results) {
+		top = len(results)
+	}
+
+	reranked := make([]*SearchResult, top)
+	copy(reranked, results[0:top])
+
+	scores, err := reranker.Rerank(reranked)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(reranked) {
+		return nil, fmt.Errorf(
+			"reranker returned %d scores for %d results",
+			len(scores),
+			len(reranked),
+		)
+	}
+	for index, result := range reranked {
+		result.Score = scores[index]
+	}
+
+	sort.SliceStable(reranked, func(a int, b int) bool {
+		return reranked[a].Score > reranked[b].Score
+	})
+
+	return append(reranked, results[top:]...), nil
+}
+
+// ===========================
+// Chain
+// ===========================
+
+// RerankerChain applies each of its rerankers in turn; each
+// reranker is given the results as ordered by the scores of
+// the one before it. Only the final scores are returned, so
+// any ties within them fall back to the original order.
+type RerankerChain struct {
+	Rerankers []Reranker
+}
+
+// ChainRerankers creates a RerankerChain of the rerankers
+func ChainRerankers(rerankers ...Reranker) *RerankerChain {
+	return &RerankerChain{
+		Rerankers: rerankers,
+	}
+}
+
+func (c *RerankerChain) Rerank(results []*SearchResult) ([]float64, error) {
+	// Work on copies so that we can reorder and rescore
+	// between each stage without touching the originals,
+	// noting where each copy came from
+	positions := map[*SearchResult]int{}
+	current := make([]*SearchResult, len(results))
+	for index, result := range results {
+		duplicate := *result
+		current[index] = &duplicate
+		positions[current[index]] = index
+	}
+
+	for _, reranker := range c.Rerankers {
+		var err error
+		current, err = rerankResults(current, reranker, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scores := make([]float64, len(results))
+	for _, result := range current {
+		scores[positions[result]] = result.Score
+	}
+	return scores, nil
+}
+
+// ===========================
+// Lexical Overlap
+// ===========================
+
+// LexicalOverlapReranker scores each result by the fraction
+// of the query's distinct terms that appear in a TEXT
+// column. Terms are lowercased runs of letters and digits.
+// It is deterministic, which makes it useful for tests or
+// for boosting exact matches such as product codes.
+type LexicalOverlapReranker struct {
+	Query  string
+	Column string
+}
+
+func (l *LexicalOverlapReranker) Rerank(results []*SearchResult) ([]float64, error) {
+	queryTerms := terms(l.Query)
+
+	scores := make([]float64, len(results))
+	if len(queryTerms) == 0 {
+		return scores, nil
+	}
+
+	for index, result := range results {
+		text, _ := result.Vector.Metadata[l.Column].(string)
+		documentTerms := terms(text)
+
+		overlap := 0
+		for term := range queryTerms {
+			if documentTerms[term] {
+				overlap++
+			}
+		}
+		scores[index] = float64(overlap) / float64(len(queryTerms))
+	}
+
+	return scores, nil
+}
+
+// terms splits text into its set of distinct lowercase terms
+func terms(text string) map[string]bool {
+	found := map[string]bool{}
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		found[field] = true
+	}
+	return found
+}
@@ -0,0 +1,103 @@
+package gsvt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseReranker scores results in reverse of their order
+type reverseReranker struct{}
+
+func (r *reverseReranker) Rerank(results []*SearchResult) ([]float64, error) {
+	scores := make([]float64, len(results))
+	for index := range results {
+		scores[index] = float64(index)
+	}
+	return scores, nil
+}
+
+// brokenReranker returns too few scores
+type brokenReranker struct{}
+
+func (b *brokenReranker) Rerank(results []*SearchResult) ([]float64, error) {
+	return []float64{}, nil
+}
+
+func TestLexicalOverlapReranker(t *testing.T) {
+	reranker := &LexicalOverlapReranker{
+		Query:  "Replacement filter XJ-900",
+		Column: "category",
+	}
+
+	results := []*SearchResult{
+		{Vector: &Vector{Metadata: map[string]interface{}{"category": "a water filter"}}},
+		{Vector: &Vector{Metadata: map[string]interface{}{"category": "xj 900 replacement filter"}}},
+		{Vector: &Vector{Metadata: map[string]interface{}{"category": "nothing relevant"}}},
+		{Vector: &Vector{Metadata: map[string]interface{}{}}},
+	}
+	scores, err := reranker.Rerank(results)
+	require.Nil(t, err)
+	assert.Equal(t, []float64{0.25, 1, 0, 0}, scores)
+}
+
+func TestSearchReranker(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{}
+	for index := 0; index < 5; index++ {
+		vectors = append(vectors, &Vector{
+			Metadata: map[string]interface{}{"category": fmt.Sprintf("item %d", index)},
+			Vector:   govector.Vector{1.0, float64(index) * 0.1, 0.0},
+		})
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+
+	// Reverse only the top 3; the rest stay put
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Reranker:          &reverseReranker{},
+		RerankTop:         3,
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 5)
+	expected := []int{2, 1, 0, 3, 4}
+	for index, result := range results.Results {
+		assert.Equal(t, vectors[expected[index]].RowID, result.Vector.RowID)
+	}
+	assert.Equal(t, 2.0, results.Results[0].Score)
+	assert.Less(t, results.Results[0].Similarity, results.Results[2].Similarity)
+
+	// Chaining a lexical match after the reversal picks
+	// out the exact match; as only the final scores count,
+	// the rest keep their original order
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Reranker: ChainRerankers(
+			&reverseReranker{},
+			&LexicalOverlapReranker{Query: "3", Column: "category"},
+		),
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 5)
+	expected = []int{3, 0, 1, 2, 4}
+	for index, result := range results.Results {
+		assert.Equal(t, vectors[expected[index]].RowID, result.Vector.RowID)
+	}
+
+	// Rerankers must return a score per result
+	_, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		Reranker:          &brokenReranker{},
+	})
+	assert.NotNil(t, err)
+}
@@ -10,6 +10,12 @@ type Schema struct {
 	Name    string
 	Columns []*Column
 	Indexes []*Index
+
+	// FullTextColumn, if set, is a TEXT column to keep a
+	// full text (FTS5) index of for hybrid search. It is
+	// managed by DB.Migrate, and is not considered when
+	// comparing schemas nor read back by FromSQL.
+	FullTextColumn string
 }
 
 type Column struct {
@@ -308,8 +314,9 @@ func (s *Schema) AlterSchemaSQL(other *Schema) []string {
 				keptColumns = append(keptColumns, col)
 			}
 		}
-		// Create our migration query
-		queries = append(queries, s.SQLMigrate(tmpTableName, keptColumns))
+		// Create our migration query, then drop the old table
+		queries = append(queries, other.SQLMigrate(tmpTableName, keptColumns))
+		queries = append(queries, fmt.Sprintf(`DROP TABLE %s`, tmpTableName))
 	} else {
 		// In this example, we have no table changes, so we can just work
 		// with add/remove indexes
@@ -368,7 +375,8 @@ func (s *Schema) GenerateDifference(other *Schema) ([]*Column, []*Column, []*Ind
 
 // SqlMigrate will generate SQL for an INSERt statement that will move
 // matching rows from the old table to the new table for a given selection
-// of columns.
+// of columns. Each row's rowid is carried over so that it still
+// identifies the same vector.
 //
 // base.Copy(other) generates SQL that takes us FROM base TO other.
 func (s *Schema) SQLMigrate(otherTableName string, columns []*Column) string {
@@ -377,16 +385,17 @@ func (s *Schema) SQLMigrate(otherTableName string, columns []*Column) string {
 	statement.WriteString(`INSERT INTO `)
 	statement.WriteString(s.Name)
 
-	statement.WriteString(`(`)
-	for index, col := range columns {
+	statement.WriteString(`(rowid`)
+	for _, col := range columns {
+		statement.WriteString(`, `)
 		statement.WriteString(col.Name)
-		if index < len(columns)-1 {
-			statement.WriteString(`, `)
-		}
 	}
 	statement.WriteString(`)`)
 
-	statement.WriteString(` SELECT `)
+	statement.WriteString(` SELECT rowid`)
+	if len(columns) > 0 {
+		statement.WriteString(`, `)
+	}
 	for index, col := range columns {
 		statement.WriteString(col.Name)
 		if index < len(columns)-1 {
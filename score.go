@@ -0,0 +1,126 @@
+package gsvt
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ScoreFunction adjusts the score of a search result, ie to
+// boost it by its metadata. It is given the vector and its
+// current score, and returns the new score. Higher scores
+// are always better to a ScoreFunction; see
+// applyScoreFunction.
+type ScoreFunction interface {
+	Score(vector *Vector, score float64) float64
+}
+
+// validateScoreFunctions ensures scores are repeatable
+// between pages if a cursor is in use; a TimeDecay measuring
+// ages from the current time would score each page
+// differently, and so skip or repeat results.
+func validateScoreFunctions(options *FilterOptions) error {
+	if options.After == nil {
+		return nil
+	}
+	for _, function := range options.ScoreFunctions {
+		if decay, ok := function.(*TimeDecay); ok && decay.Now.IsZero() {
+			return fmt.Errorf("time decay must have a fixed Now to be paged with a cursor")
+		}
+	}
+	return nil
+}
+
+// applyScoreFunction applies the function to the score. For
+// distance metrics, where lower is better, the function is
+// given the negated distance so that a boost still improves
+// the result.
+func applyScoreFunction(function ScoreFunction, vector *Vector, score float64, distance bool) float64 {
+	if distance {
+		return -function.Score(vector, -score)
+	}
+	return function.Score(vector, score)
+}
+
+// ===========================
+// Time Decay
+// ===========================
+
+// TimeDecay adds an exponentially decaying recency bonus to
+// the score, based on a TIMESTAMP column:
+//
+//	score + Weight * 0.5^(age / HalfLife)
+//
+// ...so a brand new vector gains Weight, one HalfLife old
+// gains half of Weight, and so on. Vectors without a valid
+// timestamp are left unchanged.
+type TimeDecay struct {
+	Column   string
+	HalfLife time.Duration
+	Weight   float64
+
+	// Now is the time ages are measured from. If zero,
+	// the current time is used, in which case the results
+	// can not be paged with a cursor; set it to the time
+	// of the first page instead.
+	Now time.Time
+}
+
+func (t *TimeDecay) Score(vector *Vector, score float64) float64 {
+	timestamp, ok := vector.Metadata[t.Column].(time.Time)
+	if !ok || t.HalfLife <= 0 {
+		return score
+	}
+
+	now := t.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Timestamps from the future are treated as brand new
+	age := math.Max(0, float64(now.Sub(timestamp)))
+	decay := math.Pow(0.5, age/float64(t.HalfLife))
+
+	return score + t.Weight*decay
+}
+
+// ===========================
+// Field Boost
+// ===========================
+
+// FieldBoost adds a numeric column's value, scaled by
+// Weight, to the score:
+//
+//	score + Weight * value
+//
+// Vectors without a numeric value are left unchanged.
+type FieldBoost struct {
+	Column string
+	Weight float64
+}
+
+func (f *FieldBoost) Score(vector *Vector, score float64) float64 {
+	value, ok := toFloat(vector.Metadata[f.Column])
+	if !ok {
+		return score
+	}
+	return score + f.Weight*value
+}
+
+// toFloat converts a numeric metadata value to a float64
+func toFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case float32:
+		return float64(value), true
+	case float64:
+		return value, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,146 @@
+package gsvt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreFunctions(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	decay := &TimeDecay{
+		Column:   "created_at",
+		HalfLife: 24 * time.Hour,
+		Weight:   1,
+		Now:      now,
+	}
+
+	fresh := &Vector{Metadata: map[string]interface{}{"created_at": now}}
+	dayOld := &Vector{Metadata: map[string]interface{}{"created_at": now.Add(-24 * time.Hour)}}
+	undated := &Vector{Metadata: map[string]interface{}{}}
+	assert.InDelta(t, 1.5, decay.Score(fresh, 0.5), 1e-9)
+	assert.InDelta(t, 1.0, decay.Score(dayOld, 0.5), 1e-9)
+	assert.Equal(t, 0.5, decay.Score(undated, 0.5))
+
+	boost := &FieldBoost{Column: "popularity", Weight: 0.1}
+	assert.InDelta(t, 0.7, boost.Score(&Vector{Metadata: map[string]interface{}{"popularity": int64(2)}}, 0.5), 1e-9)
+	assert.InDelta(t, 0.75, boost.Score(&Vector{Metadata: map[string]interface{}{"popularity": 2.5}}, 0.5), 1e-9)
+	assert.Equal(t, 0.5, boost.Score(&Vector{Metadata: map[string]interface{}{"popularity": "high"}}, 0.5))
+}
+
+func TestSearchScoreFunctions(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := NewDB(sqlite, &Schema{
+		Columns: []*Column{
+			{
+				Name: "created_at",
+				Type: "TIMESTAMP",
+			},
+		},
+	}, &VectorConfig{
+		Length: 3,
+	})
+	require.Nil(t, db.Migrate())
+
+	// The old memory is slightly more similar than the
+	// recent one
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := &Vector{
+		Metadata: map[string]interface{}{"created_at": now.Add(-30 * 24 * time.Hour)},
+		Vector:   govector.Vector{1.0, 0.0, 0.0},
+	}
+	recent := &Vector{
+		Metadata: map[string]interface{}{"created_at": now.Add(-time.Hour)},
+		Vector:   govector.Vector{1.0, 0.1, 0.0},
+	}
+	require.Nil(t, db.Insert(old))
+	require.Nil(t, db.Insert(recent))
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, old.RowID, results.Results[0].Vector.RowID)
+
+	// With time decay the recent memory wins, though its
+	// raw similarity is still reported
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		ScoreFunctions: []ScoreFunction{
+			&TimeDecay{
+				Column:   "created_at",
+				HalfLife: 7 * 24 * time.Hour,
+				Weight:   0.1,
+				Now:      now,
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, recent.RowID, results.Results[0].Vector.RowID)
+	assert.Greater(t, results.Results[0].Score, results.Results[0].Similarity)
+	assert.Less(t, results.Results[0].Similarity, results.Results[1].Similarity)
+
+	// For distances the bonus brings the recent memory nearer
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		ScoreFunctions: []ScoreFunction{
+			&TimeDecay{
+				Column:   "created_at",
+				HalfLife: 7 * 24 * time.Hour,
+				Weight:   0.2,
+				Now:      now,
+			},
+		},
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, recent.RowID, results.Results[0].Vector.RowID)
+	assert.Less(t, results.Results[0].Score, results.Results[0].Similarity)
+
+	// Paging needs every page scored from the same time
+	decay := &TimeDecay{
+		Column:   "created_at",
+		HalfLife: 7 * 24 * time.Hour,
+		Weight:   0.1,
+	}
+	page, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		ScoreFunctions:    []ScoreFunction{decay},
+		Limit:             1,
+	})
+	require.Nil(t, err)
+	_, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		ScoreFunctions:    []ScoreFunction{decay},
+		Limit:             1,
+		After:             page.Cursor,
+	})
+	assert.NotNil(t, err)
+
+	decay.Now = now
+	page, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		ScoreFunctions:    []ScoreFunction{decay},
+		Limit:             1,
+	})
+	require.Nil(t, err)
+	next, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		ScoreFunctions:    []ScoreFunction{decay},
+		Limit:             1,
+		After:             page.Cursor,
+	})
+	require.Nil(t, err)
+	require.Len(t, next.Results, 1)
+	assert.Equal(t, old.RowID, next.Results[0].Vector.RowID)
+}
@@ -0,0 +1,505 @@
+package gsvt
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SearchResult is a single vector returned by a similarity
+// search alongside its score.
+type SearchResult struct {
+	Vector *Vector
+
+	// Score is what the results are ranked by; it is the
+	// Similarity after any FilterOptions.ScoreFunctions
+	// are applied.
+	Score float64
+
+	// Similarity is the raw score from the metric used
+	Similarity float64
+
+	// Relevance is Similarity normalized to [0, 1] for the
+	// metric used, where 1 is the most relevant. It is
+	// only set if FilterOptions.Normalize is.
+	Relevance float64
+
+	// Collection names the collection the result came from.
+	// It is only set by FederatedSearch.
+	Collection string
+}
+
+// SearchResults is the ranked output of a similarity search.
+type SearchResults struct {
+	// Results are ordered by score, best first; highest
+	// first for similarity metrics, and lowest first for
+	// distance metrics (EUCLIDEAN). Ties are broken by rowid,
+	// lowest first, so that the ordering is stable between
+	// calls. Rerankers and MMR replace this ordering with
+	// their own.
+	Results []*SearchResult
+
+	// Cursor points at the last result returned, and is
+	// nil if there were no results. Pass it as
+	// FilterOptions.After to get the next page.
+	Cursor *SearchCursor
+
+	// Facets holds the counts for each column requested in
+	// FilterOptions.Facets, keyed by column name.
+	Facets map[string]*Facet
+
+	// Groups is set if FilterOptions.GroupBy was, and is
+	// ordered by each group's best score.
+	Groups []*SearchGroup
+
+	// Cutoff explains where and why the cutoff strategy cut
+	// the results, if one was used.
+	Cutoff *Cutoff
+
+	// Distance is set if lower Scores are better, ie for a
+	// EUCLIDEAN search that was not reranked.
+	Distance bool
+}
+
+// SearchCursor marks a position within ranked similarity
+// results by the score and rowid of the last result seen.
+type SearchCursor struct {
+	Score float64
+	RowID int64
+}
+
+// QuerySimilarity finds the vectors that match the filter
+// and ranks them by their similarity to the target. It is
+// Search, but with the results flattened into the vectors
+// and their matching similarity scores.
+func (db *DB) QuerySimilarity(target *Vector, filter *Filter, options *FilterOptions) ([]*Vector, []float64, error) {
+	results, err := db.Search(target, filter, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vectors := make([]*Vector, len(results.Results))
+	similarities := make([]float64, len(results.Results))
+	for index, result := range results.Results {
+		vectors[index] = result.Vector
+		similarities[index] = result.Score
+	}
+
+	return vectors, similarities, nil
+}
+
+// QuerySimilarityByID runs a search using a stored vector as
+// the target, looked up by the value of the schema's primary
+// key column - or by rowid if the schema has no single
+// primary key. The target row itself is excluded from the
+// results.
+func (db *DB) QuerySimilarityByID(id interface{}, filter *Filter, options *FilterOptions) (*SearchResults, error) {
+	keyColumn := ROWID_COLUMN_NAME
+	primaryKeys := []*Column{}
+	for _, column := range db.schema.Columns {
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, column)
+		}
+	}
+	if len(primaryKeys) == 1 {
+		keyColumn = primaryKeys[0].Name
+	}
+
+	// The target is the stored vector's value for the
+	// field being searched
+	field := db.fieldName(options)
+	if _, err := db.field(field); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(
+		"SELECT rowid AS %s, %s FROM %s WHERE %s = ?",
+		ROWID_COLUMN_NAME,
+		db.fieldColumn(field),
+		db.schema.Name,
+		keyColumn,
+	)
+	rows, err := db.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := db.rowsToVectors(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no vector found with %s %v", keyColumn, id)
+	}
+	if len(targets[0].Field(field)) == 0 {
+		return nil, fmt.Errorf("vector with %s %v has no value for field %s", keyColumn, id, field)
+	}
+	target := &Vector{RowID: targets[0].RowID, Vector: targets[0].Field(field)}
+
+	return db.search(target, nil, filter, options, map[int64]bool{target.RowID: true})
+}
+
+// SearchBatch runs a Search for each of the targets. The
+// candidates are loaded and decoded once, and all targets
+// are scored against them together on the worker pool. The
+// results share the targets' indexes.
+func (db *DB) SearchBatch(targets []*Vector, filter *Filter, options *FilterOptions) ([]*SearchResults, error) {
+	if options == nil {
+		options = &DefaultFilterOptions
+	}
+	options, config, err := db.fieldOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.validateFilterOptions(options); err != nil {
+		return nil, err
+	}
+	if options.Matryoshka != nil {
+		return nil, fmt.Errorf("matryoshka search is not supported by SearchBatch")
+	}
+	for _, target := range targets {
+		if err := validateFieldVector(options.Field, config, target.Vector); err != nil {
+			return nil, err
+		}
+	}
+
+	vectors, err := db.candidates(filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	vectors, projected := fieldVectors(vectors, options.Field)
+
+	similarities, err := SimilarityMatrix(targets, projected, options.SimilarityOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := make([]*SearchResults, len(targets))
+	for index := range targets {
+		batch[index], err = rankResults(targets[index], vectors, similarities[index], nil, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return batch, nil
+}
+
+// vectorScorer scores each of the candidate vectors. The
+// scores must share the candidates' indexes.
+type vectorScorer func(candidates []*Vector, options *SimilarityOptions) ([]float64, error)
+
+// Search finds the vectors that match the filter and ranks
+// them by their similarity to the target. If options is nil,
+// DefaultFilterOptions are used.
+func (db *DB) Search(target *Vector, filter *Filter, options *FilterOptions) (*SearchResults, error) {
+	return db.search(target, nil, filter, options, nil)
+}
+
+// search is the pipeline behind each similarity search; the
+// candidates matching the filter, less any excluded rowids,
+// are scored by the scorer, then ranked as per the options.
+// If the scorer is nil, candidates are scored by their
+// similarity to the target. The target may be nil if the
+// search has no single target.
+func (db *DB) search(target *Vector, scorer vectorScorer, filter *Filter, options *FilterOptions, exclude map[int64]bool) (*SearchResults, error) {
+	if options == nil {
+		options = &DefaultFilterOptions
+	}
+	options, config, err := db.fieldOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.validateFilterOptions(options); err != nil {
+		return nil, err
+	}
+	if target != nil {
+		if err := validateFieldVector(options.Field, config, target.Vector); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateMatryoshka(options, config); err != nil {
+		return nil, err
+	}
+	if options.Matryoshka != nil {
+		if scorer != nil {
+			return nil, fmt.Errorf("matryoshka search requires a single target")
+		}
+		return db.matryoshkaSearch(target, filter, options, config, exclude)
+	}
+
+	// First we get the vectors that match the filter and
+	// have a value for the field searched
+	vectors, err := db.candidates(filter, exclude)
+	if err != nil {
+		return nil, err
+	}
+	vectors, projected := fieldVectors(vectors, options.Field)
+
+	// Then we score each candidate
+	if scorer == nil {
+		scorer = target.SimilarityToVectorSet
+	}
+	similarities, err := scorer(projected, options.SimilarityOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankResults(target, vectors, similarities, nil, options)
+}
+
+func (db *DB) validateFilterOptions(options *FilterOptions) error {
+	if err := validateThresholds(options); err != nil {
+		return err
+	}
+	if err := db.validateFacets(options.Facets); err != nil {
+		return err
+	}
+	if err := db.validateGroupBy(options); err != nil {
+		return err
+	}
+	if err := validateReranker(options); err != nil {
+		return err
+	}
+	if err := validateScoreFunctions(options); err != nil {
+		return err
+	}
+	return validateMMR(options)
+}
+
+// candidates loads the vectors matching the filter, less
+// any excluded rowids
+func (db *DB) candidates(filter *Filter, exclude map[int64]bool) ([]*Vector, error) {
+	vectors, err := db.Query(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(exclude) > 0 {
+		kept := []*Vector{}
+		for _, vector := range vectors {
+			if !exclude[vector.RowID] {
+				kept = append(kept, vector)
+			}
+		}
+		vectors = kept
+	}
+	return vectors, nil
+}
+
+// rankResults takes the scored candidates and sorts, cuts
+// off, and pages them as per the options. The target may
+// be nil if the search had no single target. If scores is
+// nil the candidates are ranked by their similarities, in
+// the direction of the metric used; otherwise by the given
+// scores, highest first, ie when fusing rankings.
+func rankResults(target *Vector, vectors []*Vector, similarities []float64, scores []float64, options *FilterOptions) (*SearchResults, error) {
+	distance := false
+	if scores == nil {
+		scores = similarities
+		distance = isDistanceMetric(options.similarityOptions().Method)
+	}
+
+	// Facets are counted as we go, rather than with
+	// additional queries
+	facets := newFacets(options.Facets)
+
+	results := make([]*SearchResult, len(vectors))
+	for index, vector := range vectors {
+		results[index] = &SearchResult{
+			Vector:     vector,
+			Score:      scores[index],
+			Similarity: similarities[index],
+		}
+		for _, scoreFunction := range options.ScoreFunctions {
+			results[index].Score = applyScoreFunction(scoreFunction, vector, results[index].Score, distance)
+		}
+		if options.Normalize {
+			results[index].Relevance = normalizeRelevance(
+				options.similarityOptions(),
+				similarities[index],
+				target,
+				options.NormStatistics,
+			)
+		}
+		for _, facet := range facets {
+			facet.Candidates[vector.Metadata[facet.Column]]++
+		}
+	}
+
+	// Then we sort the results by their score
+	sortResults(results, distance)
+
+	// Cut off the less likely matches; if the std dev is
+	// not 0 and no other strategy is set, we're looking
+	// for outliers
+	strategy := options.Cutoff
+	if strategy == nil && options.StdDeviations > 0 {
+		strategy = &StdDeviationCutoff{StdDeviations: options.StdDeviations}
+	}
+	var cutoff *Cutoff
+	if strategy != nil {
+		results, cutoff = applyCutoff(results, strategy, distance)
+	}
+
+	// Drop anything outside of our absolute thresholds
+	if options.MinScore != nil || options.MaxDistance != nil {
+		results = thresholdCutoff(results, options.MinScore, options.MaxDistance)
+	}
+
+	// Second stage ranking of the survivors, if requested.
+	// Reranker scores are always higher is better, whatever
+	// the metric.
+	if options.Reranker != nil {
+		var err error
+		results, err = rerankResults(results, options.Reranker, options.RerankTop)
+		if err != nil {
+			return nil, err
+		}
+		distance = false
+	}
+
+	// Diversify the survivors if requested
+	if options.MMR != nil {
+		var err error
+		results, err = mmrRerank(results, options.MMR, options.SimilarityOptions, options.Field, distance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// If we have a cursor, drop everything up to and
+	// including it. The cutoff above is calculated over
+	// the whole candidate set so it is consistent
+	// between pages.
+	if options.After != nil {
+		results = resultsAfter(results, options.After, distance)
+	}
+
+	// Grouping handles its own limit, as it applies to
+	// the groups. Otherwise if the limit is 0 we return
+	// everything
+	var groups []*SearchGroup
+	if options.GroupBy != "" {
+		groups, results = groupResults(results, options.GroupBy, options.GroupSize, options.Limit)
+	} else if options.Limit > 0 && options.Limit < len(results) {
+		results = results[0:options.Limit]
+	}
+
+	for _, result := range results {
+		for _, facet := range facets {
+			facet.Results[result.Vector.Metadata[facet.Column]]++
+		}
+	}
+
+	searchResults := &SearchResults{
+		Results:  results,
+		Facets:   facets,
+		Groups:   groups,
+		Cutoff:   cutoff,
+		Distance: distance,
+	}
+	if len(results) > 0 {
+		searchResults.Cursor = results[len(results)-1].Cursor()
+	}
+
+	return searchResults, nil
+}
+
+// Cursor returns a SearchCursor pointing at this result
+func (r *SearchResult) Cursor() *SearchCursor {
+	return &SearchCursor{
+		Score: r.Score,
+		RowID: r.Vector.RowID,
+	}
+}
+
+// Encode converts the cursor into an opaque URL safe string
+// that can be handed to a client and decoded later via
+// DecodeSearchCursor.
+func (c *SearchCursor) Encode() string {
+	bytes := make([]byte, 16)
+	binary.LittleEndian.PutUint64(bytes[0:8], math.Float64bits(c.Score))
+	binary.LittleEndian.PutUint64(bytes[8:16], uint64(c.RowID))
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeSearchCursor reverses SearchCursor.Encode
+func DecodeSearchCursor(encoded string) (*SearchCursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) != 16 {
+		return nil, fmt.Errorf("invalid search cursor")
+	}
+
+	return &SearchCursor{
+		Score: math.Float64frombits(binary.LittleEndian.Uint64(bytes[0:8])),
+		RowID: int64(binary.LittleEndian.Uint64(bytes[8:16])),
+	}, nil
+}
+
+// isAfter determines whether a result with the given score
+// and rowid ranks after the cursor. If distance is set,
+// lower scores rank first.
+func (c *SearchCursor) isAfter(score float64, rowID int64, distance bool) bool {
+	if score != c.Score {
+		return (score < c.Score) != distance
+	}
+	return rowID > c.RowID
+}
+
+// sortResults sorts the results by score, highest first -
+// or lowest first if distance is set - with ties broken by
+// the lowest rowid
+func sortResults(results []*SearchResult, distance bool) {
+	sort.Slice(results, func(a int, b int) bool {
+		if results[a].Score != results[b].Score {
+			return (results[a].Score > results[b].Score) != distance
+		}
+		return results[a].Vector.RowID < results[b].Vector.RowID
+	})
+}
+
+// resultsAfter returns only the results that rank after
+// the cursor
+func resultsAfter(results []*SearchResult, cursor *SearchCursor, distance bool) []*SearchResult {
+	after := []*SearchResult{}
+	for _, result := range results {
+		if cursor.isAfter(result.Score, result.Vector.RowID, distance) {
+			after = append(after, result)
+		}
+	}
+	return after
+}
+
+// validateThresholds ensures that the absolute thresholds
+// match the direction of the configured metric
+func validateThresholds(options *FilterOptions) error {
+	if isDistanceMetric(options.similarityOptions().Method) {
+		if options.MinScore != nil {
+			return fmt.Errorf("MinScore can not be used with a distance metric; use MaxDistance")
+		}
+	} else if options.MaxDistance != nil {
+		return fmt.Errorf("MaxDistance can only be used with a distance metric; use MinScore")
+	}
+
+	return nil
+}
+
+// thresholdCutoff keeps only the results within the given
+// thresholds; either may be nil to ignore it.
+func thresholdCutoff(results []*SearchResult, minScore *float64, maxDistance *float64) []*SearchResult {
+	kept := []*SearchResult{}
+	for _, result := range results {
+		if minScore != nil && result.Score < *minScore {
+			continue
+		}
+		if maxDistance != nil && result.Score > *maxDistance {
+			continue
+		}
+		kept = append(kept, result)
+	}
+	return kept
+}
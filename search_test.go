@@ -0,0 +1,301 @@
+package gsvt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchCursorPaging(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Several of these vectors are identical, so their
+	// scores tie and rely on the rowid to order them
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{1.0, 1.0, 0.0}},
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{1.0, 1.0, 0.0}},
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	options := &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		StdDeviations:     0,
+		Limit:             0,
+	}
+
+	// Grab everything in one go as our expected ordering
+	all, err := db.Search(target, nil, options)
+	require.Nil(t, err)
+	require.Len(t, all.Results, len(vectors))
+	for index := 1; index < len(all.Results); index++ {
+		previous := all.Results[index-1]
+		current := all.Results[index]
+		require.GreaterOrEqual(t, previous.Score, current.Score)
+		if previous.Score == current.Score {
+			assert.Less(t, previous.Vector.RowID, current.Vector.RowID)
+		}
+	}
+
+	// Now page through two at a time, passing the cursor
+	// through its encoded form, and ensure we see the
+	// same ordering
+	options.Limit = 2
+	paged := []*SearchResult{}
+	for {
+		page, err := db.Search(target, nil, options)
+		require.Nil(t, err)
+		if len(page.Results) == 0 {
+			assert.Nil(t, page.Cursor)
+			break
+		}
+		paged = append(paged, page.Results...)
+
+		cursor, err := DecodeSearchCursor(page.Cursor.Encode())
+		require.Nil(t, err)
+		assert.Equal(t, page.Cursor, cursor)
+		options.After = cursor
+	}
+	require.Len(t, paged, len(all.Results))
+	for index, result := range paged {
+		assert.Equal(t, all.Results[index].Vector.RowID, result.Vector.RowID)
+		assert.Equal(t, all.Results[index].Score, result.Score)
+	}
+
+	// Garbage cursors fail to decode
+	_, err = DecodeSearchCursor("not a cursor")
+	assert.NotNil(t, err)
+}
+
+func TestQuerySimilarityByID(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{1.0, 0.1, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	options := &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+	}
+
+	// Without a primary key we look up by rowid
+	results, err := db.QuerySimilarityByID(vectors[0].RowID, nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, len(vectors)-1)
+	assert.Equal(t, vectors[1].RowID, results.Results[0].Vector.RowID)
+	for _, result := range results.Results {
+		assert.NotEqual(t, vectors[0].RowID, result.Vector.RowID)
+	}
+
+	_, err = db.QuerySimilarityByID(int64(12345), nil, options)
+	assert.NotNil(t, err)
+
+	// With a primary key we look up by that instead
+	keyed := NewDB(sqlite, &Schema{
+		Name: "keyed",
+		Columns: []*Column{
+			{
+				Name:       "id",
+				Type:       "TEXT",
+				PrimaryKey: true,
+			},
+		},
+	}, &VectorConfig{
+		Length: 3,
+	})
+	require.Nil(t, keyed.Migrate())
+	for index, vector := range vectors {
+		err := keyed.Insert(&Vector{
+			Metadata: map[string]interface{}{"id": fmt.Sprintf("doc_%d", index)},
+			Vector:   vector.Vector,
+		})
+		require.Nil(t, err)
+	}
+
+	results, err = keyed.QuerySimilarityByID("doc_3", nil, options)
+	require.Nil(t, err)
+	require.Len(t, results.Results, len(vectors)-1)
+	for _, result := range results.Results {
+		assert.NotEqual(t, "doc_3", result.Vector.Metadata["id"])
+	}
+}
+
+func TestSearchBatch(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	// Setup our db and vectors
+	db, _, inputs, err := setupVectorsAndDB(sqlite)
+	require.Nil(t, err)
+
+	options := &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		StdDeviations:     1.5,
+		Limit:             5,
+	}
+
+	// Each batch result should match what an individual
+	// search for that target returns
+	batch, err := db.SearchBatch(inputs, nil, options)
+	require.Nil(t, err)
+	require.Len(t, batch, len(inputs))
+	for index, input := range inputs {
+		single, err := db.Search(input, nil, options)
+		require.Nil(t, err)
+		require.Len(t, batch[index].Results, len(single.Results))
+		for position, result := range single.Results {
+			assert.Equal(t, result.Vector.RowID, batch[index].Results[position].Vector.RowID)
+			assert.InDelta(t, result.Score, batch[index].Results[position].Score, 1e-12)
+		}
+	}
+}
+
+func TestSearchThresholds(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{1.0, 0.1, 0.0}},
+		{Vector: govector.Vector{1.0, 0.2, 0.0}},
+		{Vector: govector.Vector{1.0, 1.0, 0.0}},
+		{Vector: govector.Vector{0.0, 0.0, 1.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	minScore := 0.95
+	results, err := db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		MinScore:          &minScore,
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+	for _, result := range results.Results {
+		assert.GreaterOrEqual(t, result.Score, minScore)
+	}
+
+	// Thresholds combine with the limit
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		MinScore:          &minScore,
+		Limit:             2,
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+
+	// ...and unlike the std deviation cutoff, keep every
+	// result when they all score the same
+	same := NewDB(sqlite, &Schema{Name: "same"}, &VectorConfig{Length: 3})
+	require.Nil(t, same.Migrate())
+	for i := 0; i < 3; i++ {
+		require.Nil(t, same.Insert(&Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}))
+	}
+	results, err = same.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		MinScore:          &minScore,
+	})
+	require.Nil(t, err)
+	assert.Len(t, results.Results, 3)
+
+	// Thresholds must match the direction of the metric
+	maxDistance := 0.5
+	_, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: DefaultSimilarityOptions,
+		MaxDistance:       &maxDistance,
+	})
+	assert.NotNil(t, err)
+	_, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		MinScore:          &minScore,
+	})
+	assert.NotNil(t, err)
+
+	// MaxDistance keeps only the nearest vectors
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		MaxDistance:       &maxDistance,
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 3)
+	for _, result := range results.Results {
+		assert.LessOrEqual(t, result.Score, maxDistance)
+	}
+}
+
+func TestEuclideanSearch(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	vectors := []*Vector{
+		{Vector: govector.Vector{10.0, 10.0, 10.0}},
+		{Vector: govector.Vector{1.0, 0.2, 0.0}},
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{1.0, 0.1, 0.0}},
+	}
+	db, err := setupSmallDB(sqlite, vectors)
+	require.Nil(t, err)
+
+	// The nearest vectors rank first, so a limit keeps them
+	target := &Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}
+	options := &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN, Workers: 2},
+		Limit:             3,
+	}
+	results, err := db.Search(target, nil, options)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{3, 5, 2}, fusedRowIDs(results))
+	assert.Equal(t, 0.0, results.Results[0].Score)
+
+	// ...and cursors page in the same direction
+	options.Limit = 2
+	paged := []int64{}
+	for {
+		page, err := db.Search(target, nil, options)
+		require.Nil(t, err)
+		if len(page.Results) == 0 {
+			break
+		}
+		paged = append(paged, fusedRowIDs(page)...)
+		options.After = page.Cursor
+	}
+	assert.Equal(t, []int64{3, 5, 2, 4, 1}, paged)
+
+	// Cutoffs keep the nearest outliers
+	results, err = db.Search(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN, Workers: 2},
+		StdDeviations:     0.5,
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []int64{3, 5, 2}, fusedRowIDs(results))
+	assert.LessOrEqual(t, results.Results[len(results.Results)-1].Score, results.Cutoff.Threshold)
+
+}
@@ -0,0 +1,351 @@
+package gsvt
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SPARSE_VECTOR_COLUMN_NAME is the column sparse vectors are
+// stored in if VectorConfig.Sparse is set
+const SPARSE_VECTOR_COLUMN_NAME = "sparse_vector"
+
+// SparseVector is a vector with few non-zero dimensions, ie
+// a learned sparse embedding or a set of term weights. It is
+// stored as the ascending Indexes of its non-zero dimensions
+// alongside their Values.
+type SparseVector struct {
+	Indexes []uint32
+	Values  []float64
+}
+
+// NewSparseVector builds a SparseVector from a map of
+// dimension to value. Zero values are dropped.
+func NewSparseVector(values map[uint32]float64) *SparseVector {
+	sparse := &SparseVector{
+		Indexes: []uint32{},
+		Values:  []float64{},
+	}
+	for index, value := range values {
+		if value != 0 {
+			sparse.Indexes = append(sparse.Indexes, index)
+		}
+	}
+	sort.Slice(sparse.Indexes, func(a int, b int) bool {
+		return sparse.Indexes[a] < sparse.Indexes[b]
+	})
+	for _, index := range sparse.Indexes {
+		sparse.Values = append(sparse.Values, values[index])
+	}
+	return sparse
+}
+
+// Validate ensures each index has a value and that the
+// indexes are strictly ascending.
+func (s *SparseVector) Validate() error {
+	if len(s.Indexes) != len(s.Values) {
+		return fmt.Errorf(
+			"sparse vector has %d indexes but %d values",
+			len(s.Indexes),
+			len(s.Values),
+		)
+	}
+	for i := 1; i < len(s.Indexes); i++ {
+		if s.Indexes[i] <= s.Indexes[i-1] {
+			return fmt.Errorf("sparse vector indexes must be strictly ascending")
+		}
+	}
+	return nil
+}
+
+// Dot is the dot product of the two sparse vectors. Both are
+// assumed to be valid.
+func (s *SparseVector) Dot(other *SparseVector) float64 {
+	if s == nil || other == nil {
+		return 0
+	}
+
+	// Walk both sets of indexes in step
+	product := 0.0
+	i, j := 0, 0
+	for i < len(s.Indexes) && j < len(other.Indexes) {
+		switch {
+		case s.Indexes[i] < other.Indexes[j]:
+			i++
+		case s.Indexes[i] > other.Indexes[j]:
+			j++
+		default:
+			product += s.Values[i] * other.Values[j]
+			i++
+			j++
+		}
+	}
+	return product
+}
+
+// ToBytes encodes the sparse vector as a series of 12 byte
+// pairs; a little endian uint32 index followed by its
+// float64 value.
+func (s *SparseVector) ToBytes() []byte {
+	byteArray := make([]byte, len(s.Indexes)*12)
+	for index := range s.Indexes {
+		start := index * 12
+		binary.LittleEndian.PutUint32(byteArray[start:start+4], s.Indexes[index])
+		binary.LittleEndian.PutUint64(byteArray[start+4:start+12], math.Float64bits(s.Values[index]))
+	}
+	return byteArray
+}
+
+// SparseVectorFromBytes decodes a sparse vector encoded by
+// ToBytes
+func SparseVectorFromBytes(bytes []byte) (*SparseVector, error) {
+	if len(bytes)%12 != 0 {
+		return nil, fmt.Errorf("sparse vector of %d bytes is not a multiple of 12", len(bytes))
+	}
+
+	sparse := &SparseVector{
+		Indexes: make([]uint32, len(bytes)/12),
+		Values:  make([]float64, len(bytes)/12),
+	}
+	for index := range sparse.Indexes {
+		start := index * 12
+		sparse.Indexes[index] = binary.LittleEndian.Uint32(bytes[start : start+4])
+		sparse.Values[index] = math.Float64frombits(binary.LittleEndian.Uint64(bytes[start+4 : start+12]))
+	}
+
+	return sparse, sparse.Validate()
+}
+
+// postingsTableName is the name of the posting list table for
+// the schema
+func (db *DB) postingsTableName() string {
+	return fmt.Sprintf("%s_postings", db.schema.Name)
+}
+
+// usePostings reports whether sparse vectors are indexed
+// by posting lists
+func (db *DB) usePostings() bool {
+	return db.config.Sparse && db.config.SparsePostings
+}
+
+// migrateSparse (re)creates the posting list table if
+// VectorConfig.SparsePostings is set, and drops it if not.
+// Like the full text index it is rebuilt each time, as
+// altering the table recreates it and so drops its
+// trigger. A trigger removes
+// a row's postings when it is deleted; otherwise postings
+// are only written by Insert, so sparse vectors must not be
+// updated directly.
+func (db *DB) migrateSparse() error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.migrateSparseTx(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateSparseTx is migrateSparse within the given
+// transaction
+func (db *DB) migrateSparseTx(tx *sql.Tx) error {
+	postings := db.postingsTableName()
+
+	queries := []string{
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_ad`, postings),
+		fmt.Sprintf(`DROP TABLE IF EXISTS %s`, postings),
+	}
+	if db.usePostings() {
+		queries = append(queries,
+			fmt.Sprintf(
+				`CREATE TABLE %s (dimension INTEGER NOT NULL, row_id INTEGER NOT NULL, `+
+					`value REAL NOT NULL, PRIMARY KEY (dimension, row_id)) WITHOUT ROWID`,
+				postings,
+			),
+			fmt.Sprintf(`CREATE INDEX %s_row_id ON %s (row_id)`, postings, postings),
+			fmt.Sprintf(
+				`CREATE TRIGGER %s_ad AFTER DELETE ON %s BEGIN `+
+					`DELETE FROM %s WHERE row_id = old.rowid; END`,
+				postings, db.schema.Name, postings,
+			),
+		)
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	if db.usePostings() {
+		return db.rebuildPostings(tx)
+	}
+	return nil
+}
+
+// rebuildPostings writes the postings of every existing row
+func (db *DB) rebuildPostings(tx *sql.Tx) error {
+	rows, err := tx.Query(fmt.Sprintf(
+		`SELECT rowid, %s FROM %s WHERE %s IS NOT NULL`,
+		SPARSE_VECTOR_COLUMN_NAME,
+		db.schema.Name,
+		SPARSE_VECTOR_COLUMN_NAME,
+	))
+	if err != nil {
+		return err
+	}
+
+	sparseVectors := map[int64]*SparseVector{}
+	for rows.Next() {
+		var rowID int64
+		var bytes []byte
+		if err := rows.Scan(&rowID, &bytes); err != nil {
+			rows.Close()
+			return err
+		}
+		sparse, err := SparseVectorFromBytes(bytes)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("row %d: %v", rowID, err)
+		}
+		sparseVectors[rowID] = sparse
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for rowID, sparse := range sparseVectors {
+		if err := db.insertPostings(tx, rowID, sparse); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertPostings writes a posting for each non-zero dimension
+// of the sparse vector
+func (db *DB) insertPostings(tx *sql.Tx, rowID int64, sparse *SparseVector) error {
+	if sparse == nil {
+		return nil
+	}
+	query := fmt.Sprintf(
+		`INSERT INTO %s (dimension, row_id, value) VALUES (?, ?, ?)`,
+		db.postingsTableName(),
+	)
+	for index, dimension := range sparse.Indexes {
+		if sparse.Values[index] == 0 {
+			continue
+		}
+		if _, err := tx.Exec(query, dimension, rowID, sparse.Values[index]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sparseCandidates loads the vectors matching the filter that
+// share at least one non-zero dimension with the target, via
+// the posting lists.
+func (db *DB) sparseCandidates(target *SparseVector, filter *Filter) ([]*Vector, error) {
+	if err := db.validateQueryFilter(filter); err != nil {
+		return nil, err
+	}
+
+	dimensions := ""
+	values := []interface{}{}
+	for index, dimension := range target.Indexes {
+		if target.Values[index] == 0 {
+			continue
+		}
+		if len(values) > 0 {
+			dimensions += ", "
+		}
+		dimensions += "?"
+		values = append(values, dimension)
+	}
+	if len(values) == 0 {
+		return []*Vector{}, nil
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE rowid IN (SELECT row_id FROM %s WHERE dimension IN (%s)) ",
+		db.selectClause(nil),
+		db.schema.Name,
+		db.postingsTableName(),
+		dimensions,
+	)
+	whereClause, whereValues := buildWhereClause(filter)
+	if whereClause != "" {
+		query += fmt.Sprintf("AND (%s) ", whereClause)
+		values = append(values, whereValues...)
+	}
+
+	rows, err := db.db.Query(query, values...)
+	if err != nil {
+		return nil, err
+	}
+	return db.rowsToVectors(rows)
+}
+
+// SparseSearch ranks the vectors matching the filter by the
+// dot product of their sparse vector with the target.
+// Whatever the SimilarityOptions method, scores are ranked,
+// thresholded and normalized as DOT_PRODUCT scores, so only
+// MinScore applies. If posting lists are enabled only rows
+// sharing a non-zero dimension with the target are loaded
+// and ranked, otherwise every row matching the filter is.
+// Results are otherwise cut off, paged, etc
+// as in Search. To score alongside the dense vector, Fuse
+// the results with those of a Search.
+func (db *DB) SparseSearch(target *SparseVector, filter *Filter, options *FilterOptions) (*SearchResults, error) {
+	if options == nil {
+		options = &DefaultFilterOptions
+	}
+	if !db.config.Sparse {
+		return nil, fmt.Errorf("schema %s does not store sparse vectors", db.schema.Name)
+	}
+	if target == nil {
+		return nil, fmt.Errorf("a sparse target is required")
+	}
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Scores are sparse dot products, not the dense metric
+	sparseOptions := *options
+	similarityOptions := *options.similarityOptions()
+	similarityOptions.Method = DOT_PRODUCT
+	sparseOptions.SimilarityOptions = &similarityOptions
+	options = &sparseOptions
+
+	if err := db.validateFilterOptions(options); err != nil {
+		return nil, err
+	}
+	if options.Matryoshka != nil {
+		return nil, fmt.Errorf("matryoshka search is not supported by SparseSearch")
+	}
+
+	var vectors []*Vector
+	var err error
+	if db.usePostings() {
+		vectors, err = db.sparseCandidates(target, filter)
+	} else {
+		vectors, err = db.candidates(filter, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(vectors))
+	for index, vector := range vectors {
+		scores[index] = target.Dot(vector.Sparse)
+	}
+
+	return rankResults(nil, vectors, scores, scores, options)
+}
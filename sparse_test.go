@@ -0,0 +1,225 @@
+package gsvt
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseVector(t *testing.T) {
+	sparse := NewSparseVector(map[uint32]float64{7: 2, 1: 0.5, 3: 0})
+	assert.Equal(t, []uint32{1, 7}, sparse.Indexes)
+	assert.Equal(t, []float64{0.5, 2}, sparse.Values)
+	require.Nil(t, sparse.Validate())
+
+	other := NewSparseVector(map[uint32]float64{1: 4, 2: 9, 7: 1})
+	assert.Equal(t, 4.0, sparse.Dot(other))
+	assert.Equal(t, 4.0, other.Dot(sparse))
+	assert.Equal(t, 0.0, sparse.Dot(nil))
+
+	decoded, err := SparseVectorFromBytes(sparse.ToBytes())
+	require.Nil(t, err)
+	assert.Equal(t, sparse, decoded)
+
+	_, err = SparseVectorFromBytes([]byte{1, 2, 3})
+	assert.NotNil(t, err)
+	assert.NotNil(t, (&SparseVector{Indexes: []uint32{2, 1}, Values: []float64{1, 1}}).Validate())
+	assert.NotNil(t, (&SparseVector{Indexes: []uint32{1}, Values: []float64{}}).Validate())
+}
+
+func setupSparseDB(t *testing.T, sqlite *sql.DB, postings bool) *DB {
+	db := NewDB(sqlite, &Schema{
+		Columns: []*Column{
+			{
+				Name: "category",
+				Type: "TEXT",
+			},
+		},
+	}, &VectorConfig{
+		Length:         3,
+		Sparse:         true,
+		SparsePostings: postings,
+	})
+	require.Nil(t, db.Migrate())
+
+	sparseVectors := []map[uint32]float64{
+		{1: 1.0, 100: 0.5},
+		{1: 0.2, 5000: 2.0},
+		{7: 3.0},
+		{100: 1.0, 7: 1.0},
+	}
+	for index, sparse := range sparseVectors {
+		require.Nil(t, db.Insert(&Vector{
+			Metadata: map[string]interface{}{"category": fmt.Sprintf("c%d", index%2)},
+			Vector:   govector.Vector{1.0, float64(index), 0.0},
+			Sparse:   NewSparseVector(sparse),
+		}))
+	}
+	// A row without a sparse vector
+	require.Nil(t, db.Insert(&Vector{
+		Metadata: map[string]interface{}{"category": "c0"},
+		Vector:   govector.Vector{0.0, 0.0, 1.0},
+	}))
+
+	return db
+}
+
+func TestSparseSearch(t *testing.T) {
+	for _, postings := range []bool{false, true} {
+		func() {
+			// Get our sqlite connection
+			sqlite, cleanup, err := getSqliteDB(t)
+			require.Nil(t, err)
+			defer cleanup()
+
+			db := setupSparseDB(t, sqlite, postings)
+			options := &FilterOptions{SimilarityOptions: DefaultSimilarityOptions}
+			target := NewSparseVector(map[uint32]float64{1: 1.0, 100: 2.0})
+
+			results, err := db.SparseSearch(target, nil, options)
+			require.Nil(t, err)
+			rowIDs := []int64{}
+			for _, result := range results.Results {
+				rowIDs = append(rowIDs, result.Vector.RowID)
+			}
+			if postings {
+				// Only rows sharing a dimension are loaded
+				assert.Equal(t, []int64{1, 4, 2}, rowIDs)
+			} else {
+				assert.Equal(t, []int64{1, 4, 2, 3, 5}, rowIDs)
+			}
+			assert.Equal(t, 2.0, results.Results[0].Score)
+			assert.Equal(t, 2.0, results.Results[1].Score)
+			assert.InDelta(t, 0.2, results.Results[2].Score, 1e-9)
+
+			// Sparse vectors are read back with the row
+			assert.Equal(t, []uint32{1, 100}, results.Results[0].Vector.Sparse.Indexes)
+			assert.Equal(t, govector.Vector{1.0, 0.0, 0.0}, results.Results[0].Vector.Vector)
+
+			// Filters still apply
+			filter, err := Where("category").Eq("c1").Build(db)
+			require.Nil(t, err)
+			results, err = db.SparseSearch(target, filter, options)
+			require.Nil(t, err)
+			require.Len(t, results.Results, 2)
+			assert.Equal(t, int64(4), results.Results[0].Vector.RowID)
+			assert.Equal(t, int64(2), results.Results[1].Vector.RowID)
+
+			// Alongside the dense vector via fusion
+			dense, err := db.Search(&Vector{Vector: govector.Vector{1.0, 0.0, 0.0}}, nil, options)
+			require.Nil(t, err)
+			sparse, err := db.SparseSearch(target, nil, options)
+			require.Nil(t, err)
+			fused, err := Fuse(&FusionOptions{Method: FUSION_RRF}, dense, sparse)
+			require.Nil(t, err)
+			assert.Equal(t, int64(1), fused.Results[0].Vector.RowID)
+		}()
+	}
+}
+
+func TestSparsePostings(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := setupSparseDB(t, sqlite, true)
+
+	countPostings := func() int {
+		var count int
+		require.Nil(t, sqlite.QueryRow("SELECT COUNT(*) FROM VectorCollection_postings").Scan(&count))
+		return count
+	}
+	assert.Equal(t, 7, countPostings())
+
+	// Deleting a row removes its postings
+	_, err = sqlite.Exec("DELETE FROM VectorCollection WHERE rowid = 1")
+	require.Nil(t, err)
+	assert.Equal(t, 5, countPostings())
+
+	// Migrating rebuilds them from the stored sparse vectors
+	_, err = sqlite.Exec("DELETE FROM VectorCollection_postings")
+	require.Nil(t, err)
+	require.Nil(t, db.Migrate())
+	assert.Equal(t, 5, countPostings())
+
+	// ...and turning them off drops them
+	db.config.SparsePostings = false
+	require.Nil(t, db.Migrate())
+	err = sqlite.QueryRow("SELECT COUNT(*) FROM VectorCollection_postings").Scan(new(int))
+	assert.NotNil(t, err)
+}
+
+func TestSparseValidation(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := NewDB(sqlite, &Schema{Name: "dense"}, &VectorConfig{Length: 3})
+	require.Nil(t, db.Migrate())
+
+	// Not a sparse collection
+	err = db.Insert(&Vector{
+		Vector: govector.Vector{1, 0, 0},
+		Sparse: NewSparseVector(map[uint32]float64{1: 1}),
+	})
+	assert.NotNil(t, err)
+	_, err = db.SparseSearch(NewSparseVector(map[uint32]float64{1: 1}), nil, nil)
+	assert.NotNil(t, err)
+
+	sparseDB := setupSparseDB(t, sqlite, true)
+	err = sparseDB.Insert(&Vector{
+		Vector: govector.Vector{1, 0, 0},
+		Sparse: &SparseVector{Indexes: []uint32{3, 3}, Values: []float64{1, 1}},
+	})
+	assert.NotNil(t, err)
+	_, err = sparseDB.SparseSearch(nil, nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestSparseSearchMetric(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	db := setupSparseDB(t, sqlite, false)
+	target := NewSparseVector(map[uint32]float64{1: 1.0, 100: 2.0})
+
+	// Sparse scores are dot products whatever the dense
+	// metric, so they rank highest first and MinScore
+	// thresholds them...
+	minScore := 1.0
+	results, err := db.SparseSearch(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		MinScore:          &minScore,
+	})
+	require.Nil(t, err)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, int64(1), results.Results[0].Vector.RowID)
+	assert.Equal(t, int64(4), results.Results[1].Vector.RowID)
+	assert.False(t, results.Distance)
+
+	// ...while MaxDistance does not apply
+	maxDistance := 1.0
+	_, err = db.SparseSearch(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: EUCLIDEAN},
+		MaxDistance:       &maxDistance,
+	})
+	assert.NotNil(t, err)
+
+	// Normalizing maps the unbounded dot products as
+	// DOT_PRODUCT does, rather than as cosines
+	results, err = db.SparseSearch(target, nil, &FilterOptions{
+		SimilarityOptions: &SimilarityOptions{Method: COSINE},
+		Normalize:         true,
+	})
+	require.Nil(t, err)
+	require.NotEmpty(t, results.Results)
+	assert.InDelta(t, sigmoid(2.0), results.Results[0].Relevance, 1e-9)
+}
@@ -0,0 +1,383 @@
+package gsvt
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Store manages the many collections - each a table with its
+// own DB - that may share a single SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store over an existing connection
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// OpenStore opens, creating if need be, the SQLite file at
+// the given path as a Store, via the named database/sql
+// driver, ie "sqlite3" for go-sqlite3. The driver must be
+// registered by the caller, ie by importing it.
+func OpenStore(driver string, path string) (*Store, error) {
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return NewStore(db), nil
+}
+
+// Close closes the Store's underlying connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// ===========================
+// Collections
+// ===========================
+
+// Collections returns the names of the Store's collections,
+// sorted. Collections are those in the registry, ie every
+// table migrated by gsvt; adopted tables, and the full text
+// and posting list tables kept alongside a collection, are
+// not included.
+func (s *Store) Collections() ([]string, error) {
+	names := []string{}
+	exists, err := s.Exists(REGISTRY_TABLE_NAME)
+	if err != nil || !exists {
+		return names, err
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(
+		`SELECT r.name FROM %s r JOIN sqlite_master m ON m.type='table' AND m.name=r.name ORDER BY r.name`,
+		REGISTRY_TABLE_NAME,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Exists reports whether the named table exists
+func (s *Store) Exists(name string) (bool, error) {
+	return tableExists(s.db, name)
+}
+
+func tableExists(db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?`,
+		name,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Create creates a new collection as per the schema and
+// config, and records it in the registry; it is an error if
+// the table already exists.
+func (s *Store) Create(schema *Schema, config *VectorConfig) (*DB, error) {
+	return s.CreateWithFields(schema, map[string]*VectorConfig{VECTOR_COLUMN_NAME: config})
+}
+
+// CreateWithFields is Create for a collection with several
+// named vector fields. See NewDBWithFields.
+func (s *Store) CreateWithFields(schema *Schema, fields map[string]*VectorConfig) (*DB, error) {
+	db := NewDBWithFields(s.db, schema, fields)
+
+	exists, err := s.Exists(db.schema.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("collection %s already exists", db.schema.Name)
+	}
+
+	// Migrating registers the collection
+	if err := db.Migrate(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Open opens an existing collection with the given config.
+// Its schema is read from the database, and it is not
+// migrated; it is an error if the table lacks a column the
+// config requires. Collections in the registry must be
+// opened with the config they were created with; if config
+// is nil, that config is used.
+func (s *Store) Open(name string, config *VectorConfig) (*DB, error) {
+	if config == nil {
+		return s.OpenWithFields(name, nil)
+	}
+	return s.OpenWithFields(name, map[string]*VectorConfig{VECTOR_COLUMN_NAME: config})
+}
+
+// OpenWithFields is Open for a collection with several named
+// vector fields. See NewDBWithFields.
+func (s *Store) OpenWithFields(name string, fields map[string]*VectorConfig) (*DB, error) {
+	schema, err := s.schema(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.Info(name)
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		if info == nil {
+			return nil, fmt.Errorf("collection %s is not registered, so its config must be given", name)
+		}
+		fields = info.Fields
+	}
+	if info != nil {
+		if err := validateRegistered(info, schema, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	// Anything NewDBWithFields would add is missing
+	columns := len(schema.Columns)
+	db := NewDBWithFields(s.db, schema, fields)
+	if len(schema.Columns) != columns {
+		return nil, fmt.Errorf(
+			"collection %s has no column %s",
+			name,
+			schema.Columns[columns].Name,
+		)
+	}
+
+	return db, nil
+}
+
+// schema reads the named collection's schema; see
+// collectionSchema
+func (s *Store) schema(name string) (*Schema, error) {
+	return collectionSchema(s.db, name)
+}
+
+// collectionSchema reads the named collection's schema,
+// including the full text column of its FTS5 table, if it
+// has one
+func collectionSchema(db *sql.DB, name string) (*Schema, error) {
+	schema, err := FromSQL(db, name)
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("collection %s does not exist", name)
+	}
+
+	ftsTable := fmt.Sprintf("%s_fts", name)
+	exists, err := tableExists(db, ftsTable)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		var cid int
+		var columnType string
+		var notnull int
+		var dfltValue sql.NullString
+		var pk int
+		err := db.QueryRow(fmt.Sprintf(`PRAGMA table_info("%s")`, ftsTable)).Scan(
+			&cid, &schema.FullTextColumn, &columnType, &notnull, &dfltValue, &pk,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return schema, nil
+}
+
+// Rename renames a collection, along with its indexes,
+// auxiliary tables, and registry entry, within a single
+// transaction.
+func (s *Store) Rename(from string, to string) error {
+	schema, postings, err := s.prepareCopy(from, to)
+	if err != nil {
+		return err
+	}
+	info, err := s.Info(from)
+	if err != nil {
+		return err
+	}
+	renamed := *schema
+	renamed.Name = to
+
+	return s.transaction(func(tx *sql.Tx) error {
+		if err := dropAuxiliary(tx, from); err != nil {
+			return err
+		}
+
+		// Indexes are named for their table, and so must be
+		// rebuilt under the new name
+		queries := []string{}
+		for _, index := range schema.Indexes {
+			queries = append(queries, fmt.Sprintf(`DROP INDEX IF EXISTS %s_%s`, from, index.Name))
+		}
+		queries = append(queries, fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, from, to))
+		for _, index := range schema.Indexes {
+			queries = append(queries, index.CreateIndexSQL(to))
+		}
+		if err := execQueries(tx, queries); err != nil {
+			return err
+		}
+
+		if err := buildAuxiliary(tx, &renamed, postings); err != nil {
+			return err
+		}
+
+		if info == nil {
+			return nil
+		}
+		if err := unregister(tx, from); err != nil {
+			return err
+		}
+		return writeRegistry(tx, &renamed, info.Fields, info.CreatedAt)
+	})
+}
+
+// Clone copies a collection, rowids and all, to a new
+// collection of the given name, within a single transaction.
+// A registered collection's clone is registered with the
+// same config.
+func (s *Store) Clone(from string, to string) error {
+	schema, postings, err := s.prepareCopy(from, to)
+	if err != nil {
+		return err
+	}
+	info, err := s.Info(from)
+	if err != nil {
+		return err
+	}
+	clone := *schema
+	clone.Name = to
+
+	return s.transaction(func(tx *sql.Tx) error {
+		queries := []string{clone.CreateTableSQL()}
+		for _, index := range clone.Indexes {
+			queries = append(queries, index.CreateIndexSQL(to))
+		}
+		queries = append(queries, clone.SQLMigrate(from, schema.Columns))
+		if err := execQueries(tx, queries); err != nil {
+			return err
+		}
+
+		if err := buildAuxiliary(tx, &clone, postings); err != nil {
+			return err
+		}
+
+		if info == nil {
+			return nil
+		}
+		return writeRegistry(tx, &clone, info.Fields, time.Now())
+	})
+}
+
+// Drop drops a collection, its auxiliary tables, and its
+// registry entry, within a single transaction
+func (s *Store) Drop(name string) error {
+	exists, err := s.Exists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("collection %s does not exist", name)
+	}
+
+	return s.transaction(func(tx *sql.Tx) error {
+		if err := dropAuxiliary(tx, name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`DROP TABLE %s`, name)); err != nil {
+			return err
+		}
+		return unregister(tx, name)
+	})
+}
+
+// prepareCopy checks that the collection can be renamed or
+// cloned to the new name, and returns its schema and whether
+// it keeps posting lists.
+func (s *Store) prepareCopy(from string, to string) (*Schema, bool, error) {
+	schema, err := s.schema(from)
+	if err != nil {
+		return nil, false, err
+	}
+	exists, err := s.Exists(to)
+	if err != nil {
+		return nil, false, err
+	}
+	if exists {
+		return nil, false, fmt.Errorf("collection %s already exists", to)
+	}
+	postings, err := s.Exists(fmt.Sprintf("%s_postings", from))
+	if err != nil {
+		return nil, false, err
+	}
+	return schema, postings, nil
+}
+
+// dropAuxiliary drops the full text and posting list tables,
+// and their triggers, of the named collection
+func dropAuxiliary(tx *sql.Tx, name string) error {
+	return buildAuxiliary(tx, &Schema{Name: name}, false)
+}
+
+// buildAuxiliary (re)builds the full text table of the
+// schema, if it has a FullTextColumn, and the posting lists
+// if requested.
+func buildAuxiliary(tx *sql.Tx, schema *Schema, postings bool) error {
+	db := &DB{
+		schema: schema,
+		config: &VectorConfig{Sparse: postings, SparsePostings: postings},
+	}
+	if err := db.migrateFullTextTx(tx); err != nil {
+		return err
+	}
+	return db.migrateSparseTx(tx)
+}
+
+// transaction runs fn within a single transaction, which is
+// committed only if fn succeeds
+func (s *Store) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execQueries runs each of the queries within the transaction
+func execQueries(tx *sql.Tx, queries []string) error {
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
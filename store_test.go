@@ -0,0 +1,215 @@
+package gsvt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func storeSchema(name string) *Schema {
+	category := &Column{Name: "category", Type: "TEXT"}
+	return &Schema{
+		Name:    name,
+		Columns: []*Column{category},
+		Indexes: []*Index{{Name: "category", Columns: []*Column{category}}},
+	}
+}
+
+func indexNames(t *testing.T, store *Store, table string) []string {
+	rows, err := store.db.Query(
+		`SELECT name FROM sqlite_master WHERE type='index' AND tbl_name=? AND name NOT LIKE 'sqlite_%' ORDER BY name`,
+		table,
+	)
+	require.Nil(t, err)
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		require.Nil(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestStore(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	store := NewStore(sqlite)
+	config := &VectorConfig{Length: 3, Sparse: true, SparsePostings: true}
+
+	articles, err := store.Create(storeSchema("articles"), config)
+	require.Nil(t, err)
+	for index, vector := range []govector.Vector{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+		require.Nil(t, articles.Insert(&Vector{
+			Vector:   vector,
+			Metadata: map[string]interface{}{"category": "news"},
+			Sparse:   NewSparseVector(map[uint32]float64{uint32(index): 1}),
+		}))
+	}
+	_, err = store.Create(storeSchema("comments"), &VectorConfig{Length: 3})
+	require.Nil(t, err)
+	_, err = store.Create(storeSchema("articles"), config)
+	assert.NotNil(t, err)
+
+	// Tables gsvt didn't migrate aren't collections, even
+	// with a BLOB column
+	_, err = sqlite.Exec("CREATE TABLE settings (key TEXT, value TEXT)")
+	require.Nil(t, err)
+	_, err = sqlite.Exec("CREATE TABLE images (id INTEGER, thumb BLOB)")
+	require.Nil(t, err)
+
+	names, err := store.Collections()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"articles", "comments"}, names)
+
+	// ...whereas collections named like another's auxiliary
+	// tables are
+	for _, name := range []string{"articles_fts_archive", "articles_postings_old"} {
+		_, err = store.Create(storeSchema(name), &VectorConfig{Length: 3})
+		require.Nil(t, err)
+	}
+	names, err = store.Collections()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"articles", "articles_fts_archive", "articles_postings_old", "comments"}, names)
+	for _, name := range []string{"articles_fts_archive", "articles_postings_old"} {
+		require.Nil(t, store.Drop(name))
+	}
+
+	// Opening requires a matching config
+	opened, err := store.Open("articles", config)
+	require.Nil(t, err)
+	vectors, err := opened.Query(nil)
+	require.Nil(t, err)
+	assert.Len(t, vectors, 3)
+	_, err = store.Open("comments", config)
+	assert.NotNil(t, err)
+	_, err = store.Open("missing", config)
+	assert.NotNil(t, err)
+
+	// Clones keep their rowids, indexes, and posting lists
+	require.Nil(t, store.Clone("articles", "archive"))
+	assert.NotNil(t, store.Clone("articles", "comments"))
+	assert.Equal(t, []string{"archive_category"}, indexNames(t, store, "archive"))
+	archive, err := store.Open("archive", config)
+	require.Nil(t, err)
+	results, err := archive.SparseSearch(
+		NewSparseVector(map[uint32]float64{1: 1}),
+		nil,
+		&FilterOptions{SimilarityOptions: DefaultSimilarityOptions},
+	)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 1)
+	assert.Equal(t, int64(2), results.Results[0].Vector.RowID)
+
+	// A rename that fails part way leaves the collection as
+	// it was; here the posting lists can't be rebuilt
+	_, err = sqlite.Exec("CREATE VIEW posts_postings AS SELECT 1")
+	require.Nil(t, err)
+	assert.NotNil(t, store.Rename("articles", "posts"))
+	for _, table := range []string{"articles", "articles_postings"} {
+		exists, err := store.Exists(table)
+		require.Nil(t, err)
+		assert.True(t, exists)
+	}
+	info, err := store.Info("articles")
+	require.Nil(t, err)
+	assert.NotNil(t, info)
+	_, err = sqlite.Exec("DROP VIEW posts_postings")
+	require.Nil(t, err)
+
+	// Renaming carries the indexes over to the new name
+	require.Nil(t, store.Rename("articles", "posts"))
+	assert.Equal(t, []string{"posts_category"}, indexNames(t, store, "posts"))
+	posts, err := store.Open("posts", config)
+	require.Nil(t, err)
+	results, err = posts.SparseSearch(
+		NewSparseVector(map[uint32]float64{2: 1}),
+		nil,
+		&FilterOptions{SimilarityOptions: DefaultSimilarityOptions},
+	)
+	require.Nil(t, err)
+	require.Len(t, results.Results, 1)
+	assert.Equal(t, int64(3), results.Results[0].Vector.RowID)
+
+	names, err = store.Collections()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"archive", "comments", "posts"}, names)
+
+	// Dropping removes the posting lists too
+	require.Nil(t, store.Drop("posts"))
+	assert.NotNil(t, store.Drop("posts"))
+	exists, err := store.Exists("posts_postings")
+	require.Nil(t, err)
+	assert.False(t, exists)
+	names, err = store.Collections()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"archive", "comments"}, names)
+}
+
+func TestOpenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+
+	store, err := OpenStore("sqlite3", path)
+	require.Nil(t, err)
+	_, err = store.Create(&Schema{Name: "documents"}, &VectorConfig{Length: 2})
+	require.Nil(t, err)
+	require.Nil(t, store.Close())
+
+	// Collections persist within the file
+	store, err = OpenStore("sqlite3", path)
+	require.Nil(t, err)
+	defer store.Close()
+	names, err := store.Collections()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"documents"}, names)
+
+	// The driver must be registered
+	_, err = OpenStore("missing", path)
+	assert.NotNil(t, err)
+}
+
+func TestStoreFullText(t *testing.T) {
+	// Get our sqlite connection
+	sqlite, cleanup, err := getSqliteDB(t)
+	require.Nil(t, err)
+	defer cleanup()
+
+	setupFullTextDB(t, sqlite, []*Vector{
+		{Vector: govector.Vector{1, 0, 0}, Metadata: map[string]interface{}{"category": "red apples"}},
+		{Vector: govector.Vector{0, 1, 0}, Metadata: map[string]interface{}{"category": "green pears"}},
+	})
+	store := NewStore(sqlite)
+
+	names, err := store.Collections()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"VectorCollection"}, names)
+
+	// The full text index follows the collection, and is
+	// found again on opening it
+	require.Nil(t, store.Rename("VectorCollection", "fruit"))
+	db, err := store.Open("fruit", &VectorConfig{Length: 3})
+	require.Nil(t, err)
+	assert.Equal(t, "category", db.schema.FullTextColumn)
+
+	results, err := db.HybridSearch(
+		&Vector{Vector: govector.Vector{0, 0, 1}},
+		"pears",
+		nil,
+		&FilterOptions{SimilarityOptions: DefaultSimilarityOptions},
+		nil,
+	)
+	require.Nil(t, err)
+	require.NotEmpty(t, results.Results)
+	assert.Equal(t, int64(2), results.Results[0].Vector.RowID)
+
+	exists, err := store.Exists("VectorCollection_fts")
+	require.Nil(t, err)
+	assert.False(t, exists)
+}
@@ -33,3 +33,35 @@ func getSqliteDB(t *testing.T) (*sql.DB, func(), error) {
 
 	return db, cleanup, nil
 }
+
+// setupSmallDB creates a DB of 3 dimensional vectors with a
+// "category" TEXT column and inserts the given vectors.
+func setupSmallDB(sqlite *sql.DB, vectors []*Vector) (*DB, error) {
+	db := NewDB(sqlite, &Schema{
+		Columns: []*Column{
+			{
+				Name: "category",
+				Type: "TEXT",
+			},
+		},
+	}, &VectorConfig{
+		Length: 3,
+	})
+
+	err := db.Migrate()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vector := range vectors {
+		if vector.Metadata == nil {
+			vector.Metadata = map[string]interface{}{}
+		}
+		err := db.Insert(vector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
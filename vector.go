@@ -2,6 +2,7 @@ package gsvt
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math"
 
 	"github.com/drewlanenga/govector"
@@ -12,9 +13,28 @@ const COSINE = 0
 const EUCLIDEAN = 1
 const DOT_PRODUCT = 2
 
+// isDistanceMetric reports whether lower scores are better
+// for the given method
+func isDistanceMetric(method int) bool {
+	return method == EUCLIDEAN
+}
+
 type Vector struct {
+	// RowID is the SQLite rowid of the vector. It is set
+	// once the vector is inserted or read back from the
+	// database, and is 0 otherwise.
+	RowID    int64
 	Metadata map[string]interface{}
 	Vector   govector.Vector
+
+	// Fields holds the values of any named vector fields
+	// other than the default, keyed by field name. See
+	// NewDBWithFields.
+	Fields map[string]govector.Vector
+
+	// Sparse is the vector's sparse vector, if the DB's
+	// VectorConfig.Sparse is set. It may be nil.
+	Sparse *SparseVector
 }
 
 type SimilarityOptions struct {
@@ -30,6 +50,14 @@ type SimilarityOptions struct {
 	// we ignore this and use the number of returned values
 	// instead.
 	Workers int
+
+	// MaxSim, if set, scores vectors as packed multi-vectors
+	// of MultiVectorLength long rows via late interaction;
+	// the sum, over each of the target's rows, of its best
+	// Method similarity to any of the other vector's rows.
+	// See PackMultiVector.
+	MaxSim            bool
+	MultiVectorLength int
 }
 
 var DefaultSimilarityOptions *SimilarityOptions = &SimilarityOptions{
@@ -51,29 +79,38 @@ func (v *Vector) SimilarityToVectorSet(vectors []*Vector, options *SimilarityOpt
 	if options == nil {
 		options = DefaultSimilarityOptions
 	}
-	if options.Workers == 0 {
-		options.Workers = DefaultSimilarityOptions.Workers
+	// Workers is resolved locally; the options may be shared
+	// between concurrent searches
+	workers := options.Workers
+	if workers == 0 {
+		workers = DefaultSimilarityOptions.Workers
 	}
 
 	similarities := make([]float64, len(vectors))
 	indexChannel := make(chan int)
 	var group errgroup.Group
 
-	workers := options.Workers
 	if workers > len(vectors) {
 		workers = len(vectors)
 	}
 
 	for i := 0; i < workers; i++ {
 		group.Go(func() error {
+			// We keep draining the channel on an error so
+			// that we never block the sender
+			var workerErr error
 			for index := range indexChannel {
+				if workerErr != nil {
+					continue
+				}
 				similarity, err := v.SimilarityToVector(vectors[index], options)
 				if err != nil {
-					return nil
+					workerErr = err
+					continue
 				}
 				similarities[index] = similarity
 			}
-			return nil
+			return workerErr
 		})
 	}
 
@@ -89,6 +126,71 @@ func (v *Vector) SimilarityToVectorSet(vectors []*Vector, options *SimilarityOpt
 	return similarities, nil
 }
 
+/*
+SimilarityMatrix - Given a set of targets and a set of
+vectors, find the similarity of each target to each vector.
+The result is indexed as [target][vector].
+
+The vectors are split out across options.Workers workers,
+each of which scores its vector against every target.
+
+If the options aren't specified, DEFAULTOPTIONS will be used.
+*/
+func SimilarityMatrix(targets []*Vector, vectors []*Vector, options *SimilarityOptions) ([][]float64, error) {
+	if options == nil {
+		options = DefaultSimilarityOptions
+	}
+	workers := options.Workers
+	if workers == 0 {
+		workers = DefaultSimilarityOptions.Workers
+	}
+	if workers > len(vectors) {
+		workers = len(vectors)
+	}
+
+	similarities := make([][]float64, len(targets))
+	for index := range similarities {
+		similarities[index] = make([]float64, len(vectors))
+	}
+
+	indexChannel := make(chan int)
+	var group errgroup.Group
+
+	for i := 0; i < workers; i++ {
+		group.Go(func() error {
+			// We keep draining the channel on an error so
+			// that we never block the sender; the first
+			// error is returned once all workers finish
+			var workerErr error
+			for index := range indexChannel {
+				if workerErr != nil {
+					continue
+				}
+				for target := range targets {
+					similarity, err := targets[target].SimilarityToVector(vectors[index], options)
+					if err != nil {
+						workerErr = err
+						break
+					}
+					similarities[target][index] = similarity
+				}
+			}
+			return workerErr
+		})
+	}
+
+	for index := range vectors {
+		indexChannel <- index
+	}
+	close(indexChannel)
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	return similarities, nil
+}
+
 // SimilarityToVector - Given a vector, find its similarity
 // w/ the specified method. If the options aren't specified,
 // DEFAULTOPTIONS will be used.
@@ -96,6 +198,9 @@ func (v *Vector) SimilarityToVector(other *Vector, options *SimilarityOptions) (
 	if options == nil {
 		options = DefaultSimilarityOptions
 	}
+	if options.MaxSim {
+		return v.maxSim(other, options)
+	}
 
 	switch options.Method {
 	case COSINE:
@@ -115,8 +220,20 @@ func (v *Vector) cosineSimilarity(vector *Vector) (float64, error) {
 }
 
 func (v *Vector) euclideanDistance(vector *Vector) (float64, error) {
-	// sum = v.Vector.Pow(2) + vector.Vector.Pow(2)
-	return 0.0, nil
+	if len(v.Vector) != len(vector.Vector) {
+		return 0, fmt.Errorf(
+			"vectors of length %d and %d can not be compared",
+			len(v.Vector),
+			len(vector.Vector),
+		)
+	}
+
+	sum := 0.0
+	for index, value := range v.Vector {
+		difference := value - vector.Vector[index]
+		sum += difference * difference
+	}
+	return math.Sqrt(sum), nil
 }
 
 func (v *Vector) dotProduct(vector *Vector) (float64, error) {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/drewlanenga/govector"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,3 +33,43 @@ func TestVectorSimilarity(t *testing.T) {
 		}
 	}
 }
+
+func TestEuclideanDistance(t *testing.T) {
+	base := &Vector{Vector: govector.Vector{0.0, 0.0}}
+	options := &SimilarityOptions{Method: EUCLIDEAN}
+
+	distance, err := base.SimilarityToVector(&Vector{Vector: govector.Vector{3.0, 4.0}}, options)
+	require.Nil(t, err)
+	assert.Equal(t, 5.0, distance)
+
+	_, err = base.SimilarityToVector(&Vector{Vector: govector.Vector{1.0}}, options)
+	assert.NotNil(t, err)
+}
+
+func TestSimilarityMatrix(t *testing.T) {
+	vectors := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.0, 1.0, 0.0}},
+		{Vector: govector.Vector{1.0, 1.0, 0.0}},
+	}
+	targets := []*Vector{
+		{Vector: govector.Vector{1.0, 0.0, 0.0}},
+		{Vector: govector.Vector{0.0, 2.0, 0.0}},
+	}
+	options := &SimilarityOptions{
+		Method:  DOT_PRODUCT,
+		Workers: 2,
+	}
+
+	similarities, err := SimilarityMatrix(targets, vectors, options)
+	require.Nil(t, err)
+	require.Equal(t, [][]float64{
+		{1.0, 0.0, 1.0},
+		{0.0, 2.0, 2.0},
+	}, similarities)
+
+	// Mismatched lengths error rather than hang
+	vectors = append(vectors, &Vector{Vector: govector.Vector{1.0}})
+	_, err = SimilarityMatrix(targets, vectors, options)
+	require.NotNil(t, err)
+}